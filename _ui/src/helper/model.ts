@@ -4,15 +4,67 @@ export type cell = {
   content: string;
   limit: number;
   template: template;
-  mode?: modeTransfer;
+  mode?: modeTransfer | modeScript;
   dependency?: dependency;
   enabled?: boolean;
   result?: boolean;
   description?: string; // Optional field for description
   collapsed?: boolean;
   path?: string; // Optional field for path
+  snapshot?: snapshot;
+  typed?: boolean;
+  params?: param[];
+  page_size?: number;
+  timeout?: string;
+  explain?: boolean;
+  cache?: cache;
 };
 
+export type cache = {
+  enabled: boolean;
+  ttl?: string;
+};
+
+export type cacheInfo = {
+  hit: boolean;
+  key: string;
+  cached_at: string;
+  expires_at: string;
+};
+
+export type planNode = {
+  operation: string;
+  object?: string;
+  detail?: string;
+  cost?: number;
+  rows?: number;
+  actual_rows?: number;
+  actual_time?: number;
+  children?: planNode[];
+};
+
+export type param = {
+  name: string;
+  path: string;
+  type?: "" | "string" | "integer" | "number" | "boolean";
+  default?: any;
+  required?: boolean;
+}
+
+export type columnInfo = {
+  name: string;
+  type: "integer" | "number" | "decimal" | "boolean" | "string" | "date" | "bytes" | "json" | "uuid";
+  database_type: string;
+  nullable?: boolean;
+}
+
+export type snapshot = {
+  enabled: boolean;
+  isolation?: "" | "repeatable_read" | "serializable" | "snapshot";
+  id?: string;
+  scn?: string;
+}
+
 export type cellPlus = cell & {
   cells: Record<string, cell>;
   values: Record<string, any>;
@@ -35,9 +87,30 @@ export type modeTransfer = {
   batch: number;
   wipe: boolean;
   map_type: map_type;
+  lob?: lob;
   skip_error: skip_error;
+  destinations?: destination[];
+  on_error?: "abort" | "continue";
+}
+
+export type modeScript = {
+  enabled: boolean;
+  name: "script";
+}
+
+export type destination = {
+  db_type: string;
+  table: string;
+  wipe: boolean;
+  map_type?: map_type;
 }
 
+export type lob = {
+  enabled: boolean;
+  max_size: number;
+  truncate: boolean;
+};
+
 export type skip_error = {
   enabled: boolean;
   message: string;
@@ -46,22 +119,59 @@ export type skip_error = {
 export type map_type = {
   enabled: boolean;
   column?: Record<string, {
-    type: "number" | "string" | "date";
+    type: mapTypes;
     nullable: boolean;
   }>;
   destination?: Record<string, {
-    type: "number" | "string" | "date";
+    type: mapTypes;
     nullable: boolean;
     template: enabled;
     encoding: encoding;
+    format?: string;
+    date?: date;
+    null?: nullPolicy;
+    mask?: mask;
   }>;
 }
 
-export const encodingTypes = ["ISO 8859-1"];
+export type mask = {
+  type: "" | "hash" | "redact" | "null" | "name" | "email" | "iban" | "date_shift";
+  length?: number;
+  keep?: number;
+  days?: number;
+}
+
+export type nullPolicy = {
+  empty_to_null?: boolean;
+  on_null?: "" | "default" | "empty" | "fail";
+  default?: string;
+}
+
+export type date = {
+  input_layout?: string;
+  output_layout?: string;
+  source_zone?: string;
+  destination_zone?: string;
+  date_only?: boolean;
+}
+
+export type mapTypes = "number" | "string" | "date" | "integer" | "boolean" | "json" | "uuid" | "bytes";
+
+export const encodingTypes = [
+  "ISO 8859-1",
+  "ISO 8859-9",
+  "ISO 8859-15",
+  "windows-1252",
+  "IBM037",
+  "IBM1047",
+  "UTF-16",
+  "auto",
+];
 
 export type encoding = {
   enabled: boolean;
   coding: string;
+  fallback?: string;
 }
 
 export type enabled = {
@@ -83,6 +193,53 @@ export type content = {
 export type info = {
   version: string;
   databases?: string[];
+  read_only?: string[];
+  status?: database_status[];
+};
+
+export type database_status = {
+  name: string;
+  type: string;
+  read_only?: boolean;
+  required?: boolean;
+  status: "up" | "down";
+  error?: string;
+  last_check?: string;
+};
+
+export type connection = {
+  name: string;
+  db_type: string;
+  datasource?: string;
+  db_schema?: string;
+  init_statements?: string[];
+  query_timeout?: string;
+  read_only?: boolean;
+  max_open_conns?: number;
+  max_idle_conns?: number;
+  conn_max_lifetime?: string;
+  conn_max_idle_time?: string;
+  source?: "config" | "store";
+  updated_by?: string;
+  created_at?: string;
+  updated_at?: string;
+};
+
+export type history = {
+  id: string;
+  user?: string;
+  db_type: string;
+  note?: string;
+  cell?: string;
+  kind: string;
+  query: string;
+  params?: Record<string, any>;
+  duration_ms: number;
+  rows: number;
+  rows_affected: number;
+  status: "success" | "error" | "timeout";
+  error?: string;
+  created_at: string;
 };
 
 export type idName = {
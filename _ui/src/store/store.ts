@@ -1,4 +1,4 @@
-import type { idName, info } from "@/helper/model";
+import type { cacheInfo, idName, info, planNode } from "@/helper/model";
 import { writable } from "svelte/store";
 
 let navbar = {
@@ -13,6 +13,10 @@ export type QueryOutput = {
   rows?: string[][];
   columns: string[];
   duration?: string;
+  results?: QueryOutput[];
+  next_page?: string;
+  plan?: planNode;
+  cache?: cacheInfo;
 }
 
 export const storeNavbar = writable(navbar);
@@ -4,6 +4,9 @@ import (
 	"context"
 	"fmt"
 
+	// time zones of date mapping work without tzdata in the image
+	_ "time/tzdata"
+
 	"github.com/rakunlabs/into"
 	"github.com/rakunlabs/logi"
 
@@ -42,12 +45,19 @@ func run(ctx context.Context) error {
 
 	defer collector.Shutdown()
 
-	db, err := database.Connect(ctx, cfg.Database)
-	if err != nil {
-		return fmt.Errorf("init database; %w", err)
-	}
+	// unreachable databases don't stop the start, they are retried in the background
+	db := database.Connect(ctx, cfg.Database)
 	defer db.Close()
 
+	db.MaskSalt = cfg.Mask.Salt
+	db.CheckInterval = cfg.DatabaseCheck
+
+	go db.Watch(ctx)
+
+	if err := db.RegisterPoolMetrics(); err != nil {
+		return fmt.Errorf("init pool metrics; %w", err)
+	}
+
 	st, err := store.New(ctx, cfg.Store)
 	if err != nil {
 		return fmt.Errorf("init store; %w", err)
@@ -55,6 +65,28 @@ func run(ctx context.Context) error {
 	defer st.Close()
 
 	svc := service.New(db, st)
+	svc.Cursors.IdleTimeout = cfg.Cursor.IdleTimeout
+	svc.Cursors.Max = cfg.Cursor.Max
+	svc.MaxTimeout = cfg.Query.MaxTimeout
+	svc.Cache.TTL = cfg.Cache.TTL
+	svc.Cache.MaxEntries = cfg.Cache.MaxEntries
+	svc.Cache.MaxBytes = cfg.Cache.MaxBytes
+	svc.HistoryDisabled = cfg.History.Disabled
+	svc.HistoryRetention = cfg.History.Retention
+	svc.HealthTimeout = cfg.Health.Timeout
+
+	if cfg.Secret.Key != "" {
+		svc.Secret, err = service.NewSecret(cfg.Secret.Key)
+		if err != nil {
+			return fmt.Errorf("init secret; %w", err)
+		}
+	}
+
+	if err := svc.LoadConnections(ctx); err != nil {
+		return fmt.Errorf("init connections; %w", err)
+	}
+
+	go svc.CleanHistory(ctx)
 
 	srv, err := server.New(ctx, cfg.Server, svc)
 	if err != nil {
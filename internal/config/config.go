@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"time"
 
 	"github.com/rakunlabs/chu"
 	"github.com/rakunlabs/logi"
@@ -22,7 +23,16 @@ type Config struct {
 	LogLevel string              `cfg:"log_level" default:"info"`
 	Server   Server              `cfg:"server"`
 	Database map[string]Database `cfg:"database"`
-	Store    Store               `cfg:"store"`
+	// DatabaseCheck is the period of status checks of connected databases, default 30s.
+	DatabaseCheck time.Duration `cfg:"database_check"`
+	Store         Store         `cfg:"store"`
+	Mask          Mask          `cfg:"mask"`
+	Cursor        Cursor        `cfg:"cursor"`
+	Query         Query         `cfg:"query"`
+	History       History       `cfg:"history"`
+	Cache         Cache         `cfg:"cache"`
+	Secret        Secret        `cfg:"secret"`
+	Health        Health        `cfg:"health"`
 
 	Telemetry tell.Config `cfg:"telemetry"`
 }
@@ -34,12 +44,73 @@ type Server struct {
 	Host string `cfg:"host"`
 
 	PrivateToken string `cfg:"private_token" log:"-"`
+	// AdminToken is required in the Admin-Token header of admin endpoints, they are disabled without it.
+	AdminToken string `cfg:"admin_token" log:"-"`
 }
 
 type Database struct {
 	DBDatasource string `cfg:"db_datasource" log:"-"`
 	DBType       string `cfg:"db_type"`
-	DBSchema     string `cfg:"db_schema"`
+	// DBSchema is the default schema of sessions, search_path of PostgreSQL, current schema of Oracle and database of MySQL.
+	DBSchema string `cfg:"db_schema"`
+	// InitStatements run on every new connection after the schema, like time zone and date format settings.
+	InitStatements []string `cfg:"init_statements"`
+	// QueryTimeout is the timeout of cells on this database without own timeout.
+	QueryTimeout time.Duration `cfg:"query_timeout"`
+	// ReadOnly allows only reading statements and forbids transfers to the database.
+	ReadOnly bool `cfg:"read_only"`
+	// Required databases must be up for readiness.
+	Required bool `cfg:"required"`
+
+	// Connection pool, zero values keep the defaults of 3 connections and 15m lifetime.
+	MaxOpenConns    int           `cfg:"max_open_conns"`
+	MaxIdleConns    int           `cfg:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `cfg:"conn_max_lifetime"`
+	// ConnMaxIdleTime closes connections idle for this duration, 0 keeps them.
+	ConnMaxIdleTime time.Duration `cfg:"conn_max_idle_time"`
+}
+
+type Query struct {
+	// MaxTimeout caps timeouts of cells and databases, 0 is no limit.
+	MaxTimeout time.Duration `cfg:"max_timeout"`
+}
+
+type History struct {
+	// Disabled stops recording cell runs.
+	Disabled bool `cfg:"disabled"`
+	// Retention deletes records older than this every hour, 0 keeps them.
+	Retention time.Duration `cfg:"retention"`
+}
+
+type Cache struct {
+	// TTL of cells without own ttl, default 1m.
+	TTL time.Duration `cfg:"ttl"`
+	// MaxEntries is the number of cached results, default 1000.
+	MaxEntries int `cfg:"max_entries"`
+	// MaxBytes is the estimated size of cached rows, default 64MB.
+	MaxBytes int64 `cfg:"max_bytes"`
+}
+
+type Health struct {
+	// Timeout of pings of the store and databases, default 5s.
+	Timeout time.Duration `cfg:"timeout"`
+}
+
+type Secret struct {
+	// Key encrypts datasources of databases added at runtime, changing it makes saved ones unreadable.
+	Key string `cfg:"key" log:"-"`
+}
+
+type Mask struct {
+	// Salt keys hash and fake values of masked columns.
+	Salt string `cfg:"salt" log:"-"`
+}
+
+type Cursor struct {
+	// IdleTimeout closes paged queries not fetched in this duration, default 5m.
+	IdleTimeout time.Duration `cfg:"idle_timeout"`
+	// Max is the number of open paged queries, default 64.
+	Max int `cfg:"max"`
 }
 
 type Store struct {
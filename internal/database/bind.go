@@ -0,0 +1,155 @@
+package database
+
+import (
+	"strconv"
+	"strings"
+)
+
+// BindVar returns the n-th (1 based) bind variable of the driver.
+func BindVar(dbType string, n int) string {
+	switch dbType {
+	case "pgx", "postgres":
+		return "$" + strconv.Itoa(n)
+	case "godror":
+		return ":" + strconv.Itoa(n)
+	case "sqlserver":
+		return "@p" + strconv.Itoa(n)
+	default:
+		return "?"
+	}
+}
+
+// BindNamed replaces :name parameters of query with bind variables of the driver and returns the arguments in order.
+// Only names in params are replaced; string literals, quoted identifiers, comments and :: casts are skipped.
+// Parameters not used in the query are ignored, content templates can leave them out.
+func BindNamed(dbType, query string, params map[string]any) (string, []any) {
+	if len(params) == 0 {
+		return query, nil
+	}
+
+	var (
+		b    strings.Builder
+		args []any
+	)
+
+	b.Grow(len(query))
+
+	for i := 0; i < len(query); {
+		c := query[i]
+
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			// MySQL escapes quotes with backslash in strings
+			end := skipQuoted(query, i, c, dbType == "mysql" && c != '`')
+			b.WriteString(query[i:end])
+			i = end
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query) - i
+			}
+
+			b.WriteString(query[i : i+end])
+			i += end
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				end = len(query) - i
+			} else {
+				end += 4
+			}
+
+			b.WriteString(query[i : i+end])
+			i += end
+		case c == '$' && (dbType == "pgx" || dbType == "postgres"):
+			end := skipDollarQuoted(query, i)
+			b.WriteString(query[i:end])
+			i = end
+		case c == ':' && i+1 < len(query) && query[i+1] == ':':
+			b.WriteString("::")
+			i += 2
+		case c == ':' && i+1 < len(query) && isIdentStart(query[i+1]):
+			end := i + 1
+			for end < len(query) && isIdentPart(query[end]) {
+				end++
+			}
+
+			name := query[i+1 : end]
+			v, ok := params[name]
+			if !ok {
+				b.WriteString(query[i:end])
+				i = end
+
+				continue
+			}
+
+			args = append(args, v)
+			b.WriteString(BindVar(dbType, len(args)))
+			i = end
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+
+	return b.String(), args
+}
+
+// skipQuoted returns the index after the quoted part starting at i, doubled quotes are escapes.
+func skipQuoted(query string, i int, quote byte, backslash bool) int {
+	for j := i + 1; j < len(query); j++ {
+		if backslash && query[j] == '\\' {
+			j++
+
+			continue
+		}
+
+		if query[j] != quote {
+			continue
+		}
+
+		if j+1 < len(query) && query[j+1] == quote {
+			j++
+
+			continue
+		}
+
+		return j + 1
+	}
+
+	return len(query)
+}
+
+// skipDollarQuoted returns the index after a PostgreSQL $tag$...$tag$ string starting at i.
+func skipDollarQuoted(query string, i int) int {
+	end := i + 1
+	for end < len(query) && isIdentPart(query[end]) && query[end] != '$' {
+		end++
+	}
+
+	if end >= len(query) || query[end] != '$' || (end > i+1 && isDigit(query[i+1])) {
+		// positional parameter like $1
+		return end
+	}
+
+	tag := query[i : end+1]
+
+	closing := strings.Index(query[end+1:], tag)
+	if closing < 0 {
+		return len(query)
+	}
+
+	return end + 1 + closing + len(tag)
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || isDigit(c)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,95 @@
+package database
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/worldline-go/saz/internal/service"
+)
+
+func TestBindNamed(t *testing.T) {
+	params := map[string]any{"status": "active", "id": 7}
+
+	tests := []struct {
+		name     string
+		dbType   string
+		query    string
+		want     string
+		wantArgs []any
+	}{
+		{
+			name:     "postgres",
+			dbType:   "pgx",
+			query:    "SELECT * FROM users WHERE status = :status AND id = :id OR parent = :id",
+			want:     "SELECT * FROM users WHERE status = $1 AND id = $2 OR parent = $3",
+			wantArgs: []any{"active", 7, 7},
+		},
+		{
+			name:     "oracle",
+			dbType:   "godror",
+			query:    "SELECT * FROM users WHERE status = :status",
+			want:     "SELECT * FROM users WHERE status = :1",
+			wantArgs: []any{"active"},
+		},
+		{
+			name:     "sql server",
+			dbType:   "sqlserver",
+			query:    "SELECT * FROM users WHERE status = :status AND id = :id",
+			want:     "SELECT * FROM users WHERE status = @p1 AND id = @p2",
+			wantArgs: []any{"active", 7},
+		},
+		{
+			name:     "mysql",
+			dbType:   "mysql",
+			query:    `SELECT * FROM users WHERE note = 'it\'s :status' AND status = :status`,
+			want:     `SELECT * FROM users WHERE note = 'it\'s :status' AND status = ?`,
+			wantArgs: []any{"active"},
+		},
+		{
+			name:     "skip strings comments and casts",
+			dbType:   "pgx",
+			query:    "SELECT ':status', \":id\", $$ :id $$, $1, x::text -- :status\n/* :id */ FROM t WHERE a = :status AND b = :unknown",
+			want:     "SELECT ':status', \":id\", $$ :id $$, $1, x::text -- :status\n/* :id */ FROM t WHERE a = $1 AND b = :unknown",
+			wantArgs: []any{"active"},
+		},
+		{
+			name:     "escaped quote",
+			dbType:   "sqlite3",
+			query:    "SELECT 'it''s :id' WHERE id = :id",
+			want:     "SELECT 'it''s :id' WHERE id = ?",
+			wantArgs: []any{7},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, args := BindNamed(tt.dbType, tt.query, params)
+			require.Equal(t, tt.want, got)
+			require.Equal(t, tt.wantArgs, args)
+		})
+	}
+
+	got, args := BindNamed("pgx", "SELECT :id", nil)
+	require.Equal(t, "SELECT :id", got)
+	require.Nil(t, args)
+}
+
+func TestQueryParams(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	d := &Database{DB: map[string]*Info{"sqlite": {DB: db, DBType: "sqlite3"}}}
+
+	_, err = d.Exec(t.Context(), "sqlite", "CREATE TABLE users (name TEXT)", nil)
+	require.NoError(t, err)
+
+	injection := "x'); DROP TABLE users; --"
+	_, err = d.Exec(t.Context(), "sqlite", "INSERT INTO users VALUES (:name)", map[string]any{"name": injection})
+	require.NoError(t, err)
+
+	result, err := d.Query(t.Context(), "sqlite", "SELECT name FROM users WHERE name = :name", map[string]any{"name": injection}, 0, false, service.Snapshot{})
+	require.NoError(t, err)
+	require.Equal(t, [][]any{{injection}}, result.Rows())
+}
@@ -0,0 +1,397 @@
+package database
+
+import (
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/spf13/cast"
+	"github.com/worldline-go/types"
+)
+
+const (
+	FormatBase64 = "base64"
+	FormatHex    = "hex"
+
+	// FormatBinary writes uuid as 16 bytes in RFC 4122 order.
+	FormatBinary = "binary"
+	// FormatBinaryMixed writes uuid as 16 bytes in MSSQL uniqueidentifier order.
+	FormatBinaryMixed = "binary_mixed"
+)
+
+var ErrOverflow = errors.New("value overflows")
+
+var (
+	minInt64 = decimal.NewFromInt(math.MinInt64)
+	maxInt64 = decimal.NewFromInt(math.MaxInt64)
+)
+
+// UUID scans uuid values of all drivers to the canonical string form.
+type UUID struct {
+	V     string
+	Valid bool
+
+	// mixedEndian is set for MSSQL uniqueidentifier columns,
+	// first three groups of the binary form are little endian.
+	mixedEndian bool
+}
+
+func (u *UUID) Scan(value any) error {
+	switch v := value.(type) {
+	case nil:
+		u.V, u.Valid = "", false
+
+		return nil
+	case []byte:
+		if len(v) == 16 {
+			id, err := uuidFromBytes(v, u.mixedEndian)
+			if err != nil {
+				return err
+			}
+
+			u.V, u.Valid = id.String(), true
+
+			return nil
+		}
+
+		return u.Scan(string(v))
+	case string:
+		id, err := uuid.Parse(v)
+		if err != nil {
+			return fmt.Errorf("parse uuid: %w", err)
+		}
+
+		u.V, u.Valid = id.String(), true
+
+		return nil
+	}
+
+	return fmt.Errorf("cannot scan %T into UUID", value)
+}
+
+func (u UUID) Value() (driver.Value, error) {
+	if !u.Valid {
+		return nil, nil
+	}
+
+	return u.V, nil
+}
+
+func uuidFromBytes(b []byte, mixedEndian bool) (uuid.UUID, error) {
+	id, err := uuid.FromBytes(b)
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+
+	if mixedEndian {
+		swapUUIDEndian(id[:])
+	}
+
+	return id, nil
+}
+
+// mixedEndianUUID reports whether binary uuids of the database are in MSSQL byte order.
+func mixedEndianUUID(dbType string) bool {
+	return dbType == "sqlserver" || dbType == "mssql"
+}
+
+// swapUUIDEndian converts between RFC 4122 and MSSQL byte order, the operation is its own inverse.
+func swapUUIDEndian(b []byte) {
+	reverse := func(b []byte) {
+		for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+			b[i], b[j] = b[j], b[i]
+		}
+	}
+
+	reverse(b[0:4])
+	reverse(b[4:6])
+	reverse(b[6:8])
+}
+
+// /////////////////////////////////////////////
+
+func getAnyInteger(v any, t *columnMapping) (types.Null[int64], error) {
+	var vStr string
+
+	switch val := v.(type) {
+	case nil:
+		return types.NewNullWithValid[int64](0, false), nil
+	case int64:
+		if !t.Template.Enabled {
+			return types.NewNull(val), nil
+		}
+
+		vStr = strconv.FormatInt(val, 10)
+	case types.Null[int64]:
+		if !val.Valid || !t.Template.Enabled {
+			return val, nil
+		}
+
+		vStr = strconv.FormatInt(val.V, 10)
+	case types.Decimal:
+		vStr = val.String()
+	case types.NullDecimal:
+		if !val.Valid {
+			return types.NewNullWithValid[int64](0, false), nil
+		}
+
+		vStr = val.Decimal.String()
+	case types.Null[string]:
+		if !val.Valid {
+			return types.NewNullWithValid[int64](0, false), nil
+		}
+
+		vStr = val.V
+	case float64:
+		vStr = decimal.NewFromFloat(val).String()
+	case float32:
+		vStr = decimal.NewFromFloat32(val).String()
+	case []byte:
+		vStr = string(val)
+	default:
+		vStr = cast.ToString(v)
+	}
+
+	vStr, err := t.renderValue(strings.TrimSpace(vStr))
+	if err != nil {
+		return types.Null[int64]{}, err
+	}
+
+	d, err := decimal.NewFromString(vStr)
+	if err != nil {
+		return types.Null[int64]{}, err
+	}
+
+	if !d.IsInteger() {
+		return types.Null[int64]{}, fmt.Errorf("value %s is not an integer", d.String())
+	}
+
+	if d.LessThan(minInt64) || d.GreaterThan(maxInt64) {
+		return types.Null[int64]{}, fmt.Errorf("integer %s; %w", d.String(), ErrOverflow)
+	}
+
+	return types.NewNull(d.IntPart()), nil
+}
+
+func getAnyBool(v any, t *columnMapping) (types.Null[bool], error) {
+	switch val := v.(type) {
+	case nil:
+		return types.NewNullWithValid(false, false), nil
+	case bool:
+		if !t.Template.Enabled {
+			return types.NewNull(val), nil
+		}
+	case types.Null[bool]:
+		if !val.Valid || !t.Template.Enabled {
+			return val, nil
+		}
+
+		v = val.V
+	case types.NullDecimal:
+		if !val.Valid {
+			return types.NewNullWithValid(false, false), nil
+		}
+
+		v = val.Decimal.String()
+	case types.Decimal:
+		v = val.String()
+	case types.Null[string]:
+		if !val.Valid {
+			return types.NewNullWithValid(false, false), nil
+		}
+
+		v = val.V
+	case []byte:
+		v = string(val)
+	}
+
+	vStr, err := t.renderValue(strings.TrimSpace(cast.ToString(v)))
+	if err != nil {
+		return types.Null[bool]{}, err
+	}
+
+	switch strings.ToLower(vStr) {
+	case "1", "t", "true", "y", "yes", "on":
+		return types.NewNull(true), nil
+	case "0", "f", "false", "n", "no", "off":
+		return types.NewNull(false), nil
+	}
+
+	// numeric values like 1.0 or -1
+	if d, err := decimal.NewFromString(vStr); err == nil {
+		return types.NewNull(!d.IsZero()), nil
+	}
+
+	return types.Null[bool]{}, fmt.Errorf("cannot convert %q to boolean", vStr)
+}
+
+func getAnyJSON(v any, t *columnMapping) (types.RawJSON, error) {
+	var raw []byte
+
+	switch val := v.(type) {
+	case nil:
+		return nil, nil
+	case types.RawJSON:
+		if val == nil {
+			return nil, nil
+		}
+
+		raw = val
+	case []byte:
+		raw = val
+	case string:
+		raw = []byte(val)
+	case types.Null[string]:
+		if !val.Valid {
+			return nil, nil
+		}
+
+		raw = []byte(val.V)
+	default:
+		var err error
+		raw, err = json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("marshal json: %w", err)
+		}
+	}
+
+	rendered, err := t.renderValue(string(raw))
+	if err != nil {
+		return nil, err
+	}
+
+	if !json.Valid([]byte(rendered)) {
+		return nil, fmt.Errorf("invalid json value: %q", rendered)
+	}
+
+	return types.RawJSON(rendered), nil
+}
+
+func getAnyUUID(v any, t *columnMapping) (types.Null[string], error) {
+	switch val := v.(type) {
+	case nil:
+		return types.NewNullWithValid("", false), nil
+	case UUID:
+		if !val.Valid {
+			return types.NewNullWithValid("", false), nil
+		}
+
+		v = val.V
+	case types.Null[string]:
+		if !val.Valid {
+			return types.NewNullWithValid("", false), nil
+		}
+
+		v = val.V
+	case [16]byte:
+		v = uuid.UUID(val).String()
+	case []byte:
+		if len(val) == 16 {
+			id, err := uuidFromBytes(val, t.mixedEndian)
+			if err != nil {
+				return types.Null[string]{}, err
+			}
+
+			v = id.String()
+		} else {
+			v = string(val)
+		}
+	}
+
+	vStr, err := t.renderValue(strings.TrimSpace(cast.ToString(v)))
+	if err != nil {
+		return types.Null[string]{}, err
+	}
+
+	id, err := uuid.Parse(vStr)
+	if err != nil {
+		return types.Null[string]{}, fmt.Errorf("parse uuid %q: %w", vStr, err)
+	}
+
+	return types.NewNull(id.String()), nil
+}
+
+// uuidValue returns the uuid in the destination format of the column.
+func uuidValue(v types.Null[string], t *columnMapping) any {
+	switch t.Format {
+	case FormatBinary, FormatBinaryMixed:
+		if !v.Valid {
+			return nil
+		}
+
+		id := uuid.MustParse(v.V)
+		if t.Format == FormatBinaryMixed {
+			swapUUIDEndian(id[:])
+		}
+
+		return id[:]
+	}
+
+	if t.Nullable {
+		return v
+	}
+
+	// empty string is not a uuid for the destination
+	if !v.Valid {
+		return nil
+	}
+
+	return v.V
+}
+
+func getAnyBytes(v any, t *columnMapping) ([]byte, error) {
+	var vStr string
+
+	switch val := v.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		if t.Format == "" && !t.Template.Enabled {
+			return val, nil
+		}
+
+		vStr = string(val)
+	case types.Null[string]:
+		if !val.Valid {
+			return nil, nil
+		}
+
+		vStr = val.V
+	case types.RawJSON:
+		return []byte(val), nil
+	default:
+		vStr = cast.ToString(v)
+	}
+
+	vStr, err := t.renderValue(vStr)
+	if err != nil {
+		return nil, err
+	}
+
+	switch t.Format {
+	case FormatBase64:
+		b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(vStr))
+		if err != nil {
+			return nil, fmt.Errorf("decode base64: %w", err)
+		}
+
+		return b, nil
+	case FormatHex:
+		b, err := hex.DecodeString(strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(vStr), "0x"), "\\x"))
+		if err != nil {
+			return nil, fmt.Errorf("decode hex: %w", err)
+		}
+
+		return b, nil
+	}
+
+	return []byte(vStr), nil
+}
@@ -0,0 +1,233 @@
+package database
+
+import (
+	"math"
+	"testing"
+
+	"github.com/godror/godror"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"github.com/worldline-go/saz/internal/service"
+	"github.com/worldline-go/types"
+)
+
+func TestMapDestinationTypes(t *testing.T) {
+	tests := []struct {
+		name    string
+		dbType  string
+		value   any
+		column  service.ColumnTypeTemplate
+		want    any
+		wantErr bool
+	}{
+		// integer
+		{
+			name:   "pgx int8 to integer",
+			value:  int64(42),
+			column: service.ColumnTypeTemplate{Type: "integer"},
+			want:   int64(42),
+		},
+		{
+			name:   "godror number to integer",
+			value:  godror.Number("1234"),
+			column: service.ColumnTypeTemplate{Type: "integer"},
+			want:   int64(1234),
+		},
+		{
+			name:   "decimal to integer",
+			value:  decimal.RequireFromString("-17"),
+			column: service.ColumnTypeTemplate{Type: "integer", Nullable: true},
+			want:   types.NewNull[int64](-17),
+		},
+		{
+			name:    "decimal overflow",
+			value:   decimal.RequireFromString("9223372036854775808"),
+			column:  service.ColumnTypeTemplate{Type: "integer"},
+			wantErr: true,
+		},
+		{
+			name:    "float fraction",
+			value:   1.5,
+			column:  service.ColumnTypeTemplate{Type: "integer"},
+			wantErr: true,
+		},
+		{
+			name:   "null integer",
+			value:  nil,
+			column: service.ColumnTypeTemplate{Type: "integer", Nullable: true},
+			want:   types.NewNullWithValid[int64](0, false),
+		},
+		{
+			name:   "max integer",
+			value:  uint64(math.MaxInt64),
+			column: service.ColumnTypeTemplate{Type: "integer"},
+			want:   int64(math.MaxInt64),
+		},
+		// boolean
+		{
+			name:   "godror number(1) to boolean",
+			value:  godror.Number("1"),
+			column: service.ColumnTypeTemplate{Type: "boolean"},
+			want:   true,
+		},
+		{
+			name:   "godror number(1) decimal to boolean",
+			value:  types.NullDecimal{Decimal: decimal.Zero, Valid: true},
+			column: service.ColumnTypeTemplate{Type: "boolean", Nullable: true},
+			want:   types.NewNull(false),
+		},
+		{
+			name:   "mysql tinyint to boolean",
+			value:  int64(1),
+			column: service.ColumnTypeTemplate{Type: "boolean"},
+			want:   true,
+		},
+		{
+			name:   "char flag to boolean",
+			value:  []byte("N"),
+			column: service.ColumnTypeTemplate{Type: "boolean"},
+			want:   false,
+		},
+		{
+			name:    "invalid boolean",
+			value:   "maybe",
+			column:  service.ColumnTypeTemplate{Type: "boolean"},
+			wantErr: true,
+		},
+		// json
+		{
+			name:   "pgx jsonb to json",
+			value:  []byte(`{"a":1}`),
+			column: service.ColumnTypeTemplate{Type: "json"},
+			want:   types.RawJSON(`{"a":1}`),
+		},
+		{
+			name:   "oracle clob to json",
+			value:  `[1,2]`,
+			column: service.ColumnTypeTemplate{Type: "json"},
+			want:   types.RawJSON(`[1,2]`),
+		},
+		{
+			name:    "invalid json",
+			value:   `{"a":`,
+			column:  service.ColumnTypeTemplate{Type: "json"},
+			wantErr: true,
+		},
+		{
+			name:   "null json",
+			value:  nil,
+			column: service.ColumnTypeTemplate{Type: "json"},
+			want:   types.RawJSON(nil),
+		},
+		// uuid
+		{
+			name:   "mssql binary uniqueidentifier to uuid",
+			dbType: "sqlserver",
+			value:  []byte{0xff, 0x19, 0x96, 0x6f, 0x86, 0x8b, 0x11, 0xd0, 0xb4, 0x2d, 0x00, 0xc0, 0x4f, 0xc9, 0x64, 0xff},
+			column: service.ColumnTypeTemplate{Type: "uuid"},
+			want:   "6f9619ff-8b86-d011-b42d-00c04fc964ff",
+		},
+		{
+			name:   "null uuid",
+			value:  nil,
+			column: service.ColumnTypeTemplate{Type: "uuid"},
+			want:   nil,
+		},
+		{
+			name:   "pgx uuid to uuid",
+			value:  "6F9619FF-8B86-D011-B42D-00C04FC964FF",
+			column: service.ColumnTypeTemplate{Type: "uuid"},
+			want:   "6f9619ff-8b86-d011-b42d-00c04fc964ff",
+		},
+		{
+			name:   "mssql uniqueidentifier to uuid",
+			value:  UUID{V: "6f9619ff-8b86-d011-b42d-00c04fc964ff", Valid: true},
+			column: service.ColumnTypeTemplate{Type: "uuid", Nullable: true},
+			want:   types.NewNull("6f9619ff-8b86-d011-b42d-00c04fc964ff"),
+		},
+		{
+			name:   "uuid to mssql binary",
+			value:  "6f9619ff-8b86-d011-b42d-00c04fc964ff",
+			column: service.ColumnTypeTemplate{Type: "uuid", Format: FormatBinaryMixed},
+			want:   []byte{0xff, 0x19, 0x96, 0x6f, 0x86, 0x8b, 0x11, 0xd0, 0xb4, 0x2d, 0x00, 0xc0, 0x4f, 0xc9, 0x64, 0xff},
+		},
+		{
+			name:   "oracle raw(16) to uuid",
+			value:  []byte{0x6f, 0x96, 0x19, 0xff, 0x8b, 0x86, 0xd0, 0x11, 0xb4, 0x2d, 0x00, 0xc0, 0x4f, 0xc9, 0x64, 0xff},
+			column: service.ColumnTypeTemplate{Type: "uuid"},
+			want:   "6f9619ff-8b86-d011-b42d-00c04fc964ff",
+		},
+		{
+			name:    "invalid uuid",
+			value:   "not-uuid",
+			column:  service.ColumnTypeTemplate{Type: "uuid"},
+			wantErr: true,
+		},
+		// bytes
+		{
+			name:   "raw bytes",
+			value:  []byte{0x01, 0x02},
+			column: service.ColumnTypeTemplate{Type: "bytes"},
+			want:   []byte{0x01, 0x02},
+		},
+		{
+			name:   "base64 to bytes",
+			value:  "AQI=",
+			column: service.ColumnTypeTemplate{Type: "bytes", Format: FormatBase64},
+			want:   []byte{0x01, 0x02},
+		},
+		{
+			name:   "hex to bytes",
+			value:  types.NewNull(`\x0102`),
+			column: service.ColumnTypeTemplate{Type: "bytes", Format: FormatHex},
+			want:   []byte{0x01, 0x02},
+		},
+		{
+			name:    "invalid hex",
+			value:   "zz",
+			column:  service.ColumnTypeTemplate{Type: "bytes", Format: FormatHex},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			row := []any{tt.value}
+
+			plan, err := compileMapping(tt.dbType, []string{"col"}, service.MapType{
+				Enabled:     true,
+				Destination: map[string]service.ColumnTypeTemplate{"col": tt.column},
+			}, "")
+			require.NoError(t, err)
+
+			err = plan.apply(row)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.want, row[0])
+		})
+	}
+}
+
+func TestUUIDScan(t *testing.T) {
+	// uniqueidentifier 6F9619FF-8B86-D011-B42D-00C04FC964FF as sent by MSSQL
+	mssqlBytes := []byte{0xff, 0x19, 0x96, 0x6f, 0x86, 0x8b, 0x11, 0xd0, 0xb4, 0x2d, 0x00, 0xc0, 0x4f, 0xc9, 0x64, 0xff}
+
+	v := UUID{mixedEndian: true}
+	require.NoError(t, v.Scan(mssqlBytes))
+	require.Equal(t, "6f9619ff-8b86-d011-b42d-00c04fc964ff", v.V)
+
+	v = UUID{}
+	require.NoError(t, v.Scan("{6F9619FF-8B86-D011-B42D-00C04FC964FF}"))
+	require.Equal(t, "6f9619ff-8b86-d011-b42d-00c04fc964ff", v.V)
+
+	require.NoError(t, v.Scan(nil))
+	require.False(t, v.Valid)
+
+	value, err := v.Value()
+	require.NoError(t, err)
+	require.Nil(t, value)
+}
@@ -0,0 +1,122 @@
+package database
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/worldline-go/saz/internal/service"
+)
+
+// dateLayouts are tried in order when no input layout is given, same as types.Time.
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	time.RFC3339,
+	time.DateTime,
+	time.DateOnly,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05.000000",
+}
+
+// layoutNames are accepted in place of Go reference layouts.
+var layoutNames = map[string]string{
+	"RFC3339":     time.RFC3339,
+	"RFC3339Nano": time.RFC3339Nano,
+	"DateTime":    time.DateTime,
+	"DateOnly":    time.DateOnly,
+	"TimeOnly":    time.TimeOnly,
+	"RFC1123":     time.RFC1123,
+	"RFC1123Z":    time.RFC1123Z,
+}
+
+var locations sync.Map
+
+func layout(name string) string {
+	if v, ok := layoutNames[name]; ok {
+		return v
+	}
+
+	return name
+}
+
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return nil, nil
+	}
+
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("load time zone %s: %w", name, err)
+	}
+
+	locations.Store(name, loc)
+
+	return loc, nil
+}
+
+// parseDate parses s with the input layout or guesses the layout.
+// Values without zone information are in the source zone, UTC if not set.
+func parseDate(s string, d service.Date) (time.Time, error) {
+	loc, err := loadLocation(d.SourceZone)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	if d.InputLayout != "" {
+		v, err := time.ParseInLocation(layout(d.InputLayout), s, loc)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("parse date with layout %s: %w", d.InputLayout, err)
+		}
+
+		return v, nil
+	}
+
+	for _, l := range dateLayouts {
+		if v, err := time.ParseInLocation(l, s, loc); err == nil {
+			return v, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid time format: %s", s)
+}
+
+// convertDate moves v to the destination zone and truncates it to the date.
+// When fromDriver is true, zone of v is replaced with the source zone keeping the wall clock.
+func convertDate(v time.Time, d service.Date, fromDriver bool) (time.Time, error) {
+	if fromDriver && d.SourceZone != "" {
+		loc, err := loadLocation(d.SourceZone)
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		v = time.Date(v.Year(), v.Month(), v.Day(), v.Hour(), v.Minute(), v.Second(), v.Nanosecond(), loc)
+	}
+
+	if d.DestinationZone != "" {
+		loc, err := loadLocation(d.DestinationZone)
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		v = v.In(loc)
+	}
+
+	if d.DateOnly {
+		v = time.Date(v.Year(), v.Month(), v.Day(), 0, 0, 0, 0, v.Location())
+	}
+
+	return v, nil
+}
+
+// formatDate returns v with the output layout, used when destination column expects text.
+func formatDate(v time.Time, d service.Date) string {
+	return v.Format(layout(d.OutputLayout))
+}
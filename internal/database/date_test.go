@@ -0,0 +1,110 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/worldline-go/saz/internal/service"
+	"github.com/worldline-go/types"
+)
+
+func TestMapDestinationDate(t *testing.T) {
+	istanbul, err := time.LoadLocation("Europe/Istanbul")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		value   any
+		date    service.Date
+		want    any
+		wantErr bool
+	}{
+		{
+			name:  "guess layout",
+			value: "2024-03-01 10:30:00",
+			want:  time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC),
+		},
+		{
+			name:  "input layout",
+			value: "01/03/2024 10:30",
+			date:  service.Date{InputLayout: "02/01/2006 15:04"},
+			want:  time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC),
+		},
+		{
+			name:    "input layout mismatch",
+			value:   "2024-03-01",
+			date:    service.Date{InputLayout: "02/01/2006"},
+			wantErr: true,
+		},
+		{
+			name:  "oracle date in local zone",
+			value: time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC),
+			date:  service.Date{SourceZone: "Europe/Istanbul", DestinationZone: "UTC"},
+			want:  time.Date(2024, 3, 1, 7, 30, 0, 0, time.UTC),
+		},
+		{
+			name:  "text without zone in source zone",
+			value: "2024-03-01 10:30:00",
+			date:  service.Date{SourceZone: "Europe/Istanbul"},
+			want:  time.Date(2024, 3, 1, 10, 30, 0, 0, istanbul),
+		},
+		{
+			name:  "text with zone keeps offset",
+			value: "2024-03-01T10:30:00Z",
+			date:  service.Date{SourceZone: "Europe/Istanbul", DestinationZone: "Europe/Istanbul"},
+			want:  time.Date(2024, 3, 1, 13, 30, 0, 0, istanbul),
+		},
+		{
+			name:  "date only",
+			value: time.Date(2024, 3, 1, 23, 30, 0, 0, time.UTC),
+			date:  service.Date{DestinationZone: "Europe/Istanbul", DateOnly: true},
+			want:  time.Date(2024, 3, 2, 0, 0, 0, 0, istanbul),
+		},
+		{
+			name:  "output layout",
+			value: types.NewTime(time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC)),
+			date:  service.Date{OutputLayout: "DateOnly"},
+			want:  "2024-03-01",
+		},
+		{
+			name:    "unknown zone",
+			value:   time.Now(),
+			date:    service.Date{DestinationZone: "Mars/Olympus"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			row := []any{tt.value}
+
+			plan, err := compileMapping("", []string{"col"}, service.MapType{
+				Enabled: true,
+				Destination: map[string]service.ColumnTypeTemplate{
+					"col": {Type: "date", Date: tt.date},
+				},
+			}, "")
+			if err == nil {
+				err = plan.apply(row)
+			}
+
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+
+			if want, ok := tt.want.(time.Time); ok {
+				got := row[0].(types.Time).Time
+				require.True(t, want.Equal(got), "want %v, got %v", want, got)
+				require.Equal(t, want.Location().String(), got.Location().String())
+
+				return
+			}
+
+			require.Equal(t, tt.want, row[0])
+		})
+	}
+}
@@ -3,11 +3,14 @@ package database
 import (
 	"context"
 	"database/sql"
-	"fmt"
 	"log/slog"
+	"maps"
+	"sync"
+	"sync/atomic"
+	"time"
 
-	"github.com/worldline-go/conn/database"
 	"github.com/worldline-go/saz/internal/config"
+	"github.com/worldline-go/saz/internal/service"
 
 	_ "github.com/alexbrainman/odbc"
 	_ "github.com/go-sql-driver/mysql"
@@ -18,44 +21,167 @@ import (
 )
 
 type Database struct {
+	// DB is the databases by name, it is changed at runtime under mu.
 	DB map[string]*Info
+	// MaskSalt keys hash and fake values of masked columns.
+	MaskSalt string
+	// CheckInterval is the period of status checks of connected databases, default 30s.
+	CheckInterval time.Duration
+
+	mu sync.RWMutex
 }
 
 type Info struct {
+	// DB is nil until the database is connected, it is not changed after.
+	// Changes of the connection settings replace the whole Info, requests holding the old one finish on its pool.
 	DB          *sql.DB
+	DBType      string
 	PlaceHolder string
+	// QueryTimeout is the default timeout of cells, 0 is no timeout.
+	QueryTimeout time.Duration
+	// ReadOnly rejects statements which can write and transfers to the database.
+	ReadOnly bool
+
+	name   string
+	config config.Database
+
+	mu       sync.Mutex
+	status   service.DatabaseStatus
+	backoff  time.Duration
+	retryAt  time.Time
+	checking atomic.Bool
+	// connecting is closed when the running connect ends.
+	connecting chan struct{}
+	// closed is set when the database is removed or replaced.
+	closed bool
+	// refs counts requests using the pool, a replaced pool is closed after them.
+	refs sync.WaitGroup
 }
 
 func (d *Database) Close() {
-	for name, dbConn := range d.DB {
-		if err := dbConn.DB.Close(); err != nil {
-			slog.Error("close database connection", "name", name, "error", err)
-		}
+	for _, dbConn := range d.infos() {
+		dbConn.close()
+	}
+}
+
+// close closes the connection pool, running queries end before their connections are closed.
+func (i *Info) close() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.closed = true
+	if i.DB == nil {
+		return
+	}
+
+	if err := i.DB.Close(); err != nil {
+		slog.Error("close database connection", "name", i.name, "error", err)
+	}
+}
+
+// retire marks the removed or replaced database closed and closes its pool after the requests using it.
+func (i *Info) retire() {
+	i.mu.Lock()
+	i.closed = true
+	i.mu.Unlock()
+
+	go func() {
+		i.refs.Wait()
+		i.close()
+	}()
+}
+
+// acquire adds a request using the pool, false when the database is closed.
+func (i *Info) acquire() bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.closed {
+		return false
 	}
+
+	i.refs.Add(1)
+
+	return true
+}
+
+// release ends the request using the pool, connections taken by the request keep working after the pool is closed.
+func (i *Info) release() {
+	i.refs.Done()
 }
 
-func Connect(ctx context.Context, cfg map[string]config.Database) (*Database, error) {
+// Connect tries to connect to the databases at the same time.
+// Databases which can't be connected are unavailable until a retry in the background or of a query connects them.
+func Connect(ctx context.Context, cfg map[string]config.Database) *Database {
 	db := &Database{
 		DB: make(map[string]*Info),
 	}
 
 	for name, dbConfig := range cfg {
-		dbConn, err := database.Connect(ctx, dbConfig.DBType, dbConfig.DBDatasource)
-		if err != nil {
-			db.Close()
+		db.DB[name] = newInfo(name, dbConfig)
+	}
+
+	var wg sync.WaitGroup
+	for _, dbConn := range db.DB {
+		wg.Go(func() {
+			_ = dbConn.connect(ctx)
+		})
+	}
+
+	wg.Wait()
+
+	return db
+}
+
+func newInfo(name string, cfg config.Database) *Info {
+	if cfg.DBSchema != "" && !schemaSupported(cfg.DBType) {
+		slog.Warn("db_schema is ignored, database has no session schema, use init_statements", "name", name, "type", cfg.DBType)
+	}
+
+	return &Info{
+		DBType:       cfg.DBType,
+		PlaceHolder:  PlaceHolder(cfg.DBType),
+		QueryTimeout: cfg.QueryTimeout,
+		ReadOnly:     cfg.ReadOnly,
+		name:         name,
+		config:       cfg,
+	}
+}
+
+// info returns the database with the name.
+func (d *Database) info(name string) (*Info, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	dbConn, ok := d.DB[name]
+
+	return dbConn, ok
+}
 
-			return nil, fmt.Errorf("connect to database %s: %w", name, err)
-		}
+// infos returns a copy of the databases to iterate without holding the lock.
+func (d *Database) infos() map[string]*Info {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
 
-		slog.Info("connected to database", "name", name, "type", dbConfig.DBType)
+	return maps.Clone(d.DB)
+}
+
+// QueryTimeout returns the default timeout of cells on the database.
+func (d *Database) QueryTimeout(name string) time.Duration {
+	if dbConn, ok := d.info(name); ok {
+		return dbConn.QueryTimeout
+	}
+
+	return 0
+}
 
-		db.DB[name] = &Info{
-			DB:          dbConn,
-			PlaceHolder: PlaceHolder(dbConfig.DBType),
-		}
+// ReadOnly reports whether the database is read only.
+func (d *Database) ReadOnly(name string) bool {
+	if dbConn, ok := d.info(name); ok {
+		return dbConn.ReadOnly
 	}
 
-	return db, nil
+	return false
 }
 
 func PlaceHolder(dbType string) string {
@@ -0,0 +1,577 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/worldline-go/saz/internal/service"
+	"github.com/worldline-go/types"
+)
+
+// Explain returns the plan of the query with the rows of the plan output of the database.
+// PostgreSQL analyzes the query by running it, it runs in a transaction rolled back after.
+func (d *Database) Explain(ctx context.Context, name, query string, params map[string]any) (service.Result, error) {
+	dbConn, err := d.conn(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	defer dbConn.release()
+
+	query = strings.TrimRight(strings.TrimSpace(query), ";")
+
+	// analyzed queries run, plan of a write is not allowed either
+	if err := checkReadOnly(name, dbConn, query); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+
+	var result *Result
+
+	switch dbConn.DBType {
+	case "pgx", "postgres":
+		result, err = explainPostgres(ctx, dbConn, query, params)
+	case "mysql":
+		result, err = explainMySQL(ctx, dbConn, query, params)
+	case "sqlserver":
+		result, err = explainSQLServer(ctx, dbConn, query, params)
+	case "godror":
+		result, err = explainOracle(ctx, dbConn, query)
+	case "sqlite3":
+		result, err = explainSQLite(ctx, dbConn, query, params)
+	default:
+		return nil, fmt.Errorf("explain is not supported for %s; %w", dbConn.DBType, service.ErrBadRequest)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("explain query on database %s: %w", name, err)
+	}
+
+	result.duration = time.Since(start)
+
+	return result, nil
+}
+
+// queryTextRollback returns the text of the single row result of query, changes of the query are rolled back.
+func queryTextRollback(ctx context.Context, dbConn *Info, query string, args []any) (string, error) {
+	tx, err := dbConn.DB.BeginTx(ctx, &sql.TxOptions{ReadOnly: readOnlyTx(dbConn)})
+	if err != nil {
+		return "", fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var text string
+	if err := tx.QueryRowContext(ctx, query, args...).Scan(&text); err != nil {
+		return "", err
+	}
+
+	return text, nil
+}
+
+func explainPostgres(ctx context.Context, dbConn *Info, query string, params map[string]any) (*Result, error) {
+	query, args := BindNamed(dbConn.DBType, query, params)
+
+	text, err := queryTextRollback(ctx, dbConn, "EXPLAIN (ANALYZE, FORMAT JSON) "+query, args)
+	if err != nil {
+		return nil, err
+	}
+
+	plan, err := postgresPlan(text)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Result{columns: []string{"QUERY PLAN"}, rows: [][]any{{text}}, plan: plan}, nil
+}
+
+func explainMySQL(ctx context.Context, dbConn *Info, query string, params map[string]any) (*Result, error) {
+	query, args := BindNamed(dbConn.DBType, query, params)
+
+	text, err := queryTextRollback(ctx, dbConn, "EXPLAIN FORMAT=JSON "+query, args)
+	if err != nil {
+		return nil, err
+	}
+
+	plan, err := mysqlPlan(text)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Result{columns: []string{"EXPLAIN"}, rows: [][]any{{text}}, plan: plan}, nil
+}
+
+// discardConn closes the driver connection of conn instead of returning it to the pool.
+func discardConn(conn *sql.Conn) {
+	_ = conn.Raw(func(any) error {
+		return driver.ErrBadConn
+	})
+}
+
+func explainSQLServer(ctx context.Context, dbConn *Info, query string, params map[string]any) (*Result, error) {
+	query, args := BindNamed(dbConn.DBType, query, params)
+
+	// showplan is a session setting, the query is compiled but not run
+	conn, err := dbConn.DB.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SET SHOWPLAN_XML ON"); err != nil {
+		return nil, fmt.Errorf("enable showplan: %w", err)
+	}
+
+	defer func() {
+		if _, err := conn.ExecContext(context.WithoutCancel(ctx), "SET SHOWPLAN_XML OFF"); err != nil {
+			// connection still in showplan mode would only compile queries
+			discardConn(conn)
+		}
+	}()
+
+	rows, err := conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var plans []string
+	for {
+		for rows.Next() {
+			var text string
+			if err := rows.Scan(&text); err != nil {
+				return nil, fmt.Errorf("scan plan: %w", err)
+			}
+
+			plans = append(plans, text)
+		}
+
+		if !rows.NextResultSet() {
+			break
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate rows: %w", err)
+	}
+
+	result := &Result{columns: []string{"Microsoft SQL Server 2005 XML Showplan"}, rows: [][]any{}}
+
+	var nodes []*service.PlanNode
+	for _, text := range plans {
+		result.rows = append(result.rows, []any{text})
+
+		plan, err := sqlServerPlan(text)
+		if err != nil {
+			return nil, err
+		}
+
+		nodes = append(nodes, plan.Children...)
+	}
+
+	result.plan = planRoot(nodes)
+
+	return result, nil
+}
+
+func explainOracle(ctx context.Context, dbConn *Info, query string) (*Result, error) {
+	// plan table rows are rolled back, statement id is at most 30 chars
+	statementID := "saz_" + strings.ToLower(ulid.Make().String())
+
+	tx, err := dbConn.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// parameters stay as bind names, the query is not run
+	if _, err := tx.ExecContext(ctx, "EXPLAIN PLAN SET STATEMENT_ID = '"+statementID+"' FOR "+query); err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT id, parent_id, operation, options, object_name, cost, cardinality, access_predicates, filter_predicates
+		FROM plan_table WHERE statement_id = :1 ORDER BY id`, statementID)
+	if err != nil {
+		return nil, fmt.Errorf("read plan table: %w", err)
+	}
+
+	var items []planItem
+	for rows.Next() {
+		var (
+			id                               int64
+			parentID                         sql.NullInt64
+			operation                        string
+			options, objectName              sql.NullString
+			cost, cardinality                sql.NullFloat64
+			accessPredicate, filterPredicate sql.NullString
+		)
+
+		if err := rows.Scan(&id, &parentID, &operation, &options, &objectName, &cost, &cardinality, &accessPredicate, &filterPredicate); err != nil {
+			rows.Close()
+
+			return nil, fmt.Errorf("scan plan: %w", err)
+		}
+
+		node := &service.PlanNode{
+			Operation: strings.TrimSpace(operation + " " + options.String),
+			Object:    objectName.String,
+			Detail:    joinDetail("access", accessPredicate.String, "filter", filterPredicate.String),
+		}
+
+		if cost.Valid {
+			node.Cost = types.NewNull(cost.Float64)
+		}
+
+		if cardinality.Valid {
+			node.Rows = types.NewNull(cardinality.Float64)
+		}
+
+		items = append(items, planItem{id: id, parent: parentID.Int64, hasParent: parentID.Valid, node: node})
+	}
+
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate plan: %w", err)
+	}
+
+	outputRows, err := tx.QueryContext(ctx, "SELECT plan_table_output FROM TABLE(DBMS_XPLAN.DISPLAY('PLAN_TABLE', :1, 'TYPICAL'))", statementID)
+	if err != nil {
+		return nil, fmt.Errorf("display plan: %w", err)
+	}
+	defer outputRows.Close()
+
+	result, err := readResultSet(outputRows, 0, false)
+	if err != nil {
+		return nil, err
+	}
+
+	result.plan = planTree(items)
+
+	return result, nil
+}
+
+func explainSQLite(ctx context.Context, dbConn *Info, query string, params map[string]any) (*Result, error) {
+	query, args := BindNamed(dbConn.DBType, query, params)
+
+	rows, err := dbConn.DB.QueryContext(ctx, "EXPLAIN QUERY PLAN "+query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result, err := readResultSet(rows, 0, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate rows: %w", err)
+	}
+
+	// columns are id, parent, notused, detail
+	items := make([]planItem, 0, len(result.rows))
+	for _, row := range result.rows {
+		if len(row) < 4 {
+			continue
+		}
+
+		id, _ := strconv.ParseInt(fmt.Sprint(row[0]), 10, 64)
+		parent, _ := strconv.ParseInt(fmt.Sprint(row[1]), 10, 64)
+
+		items = append(items, planItem{
+			id:        id,
+			parent:    parent,
+			hasParent: parent != 0,
+			node:      &service.PlanNode{Operation: fmt.Sprint(row[3])},
+		})
+	}
+
+	result.plan = planTree(items)
+
+	return result, nil
+}
+
+// /////////////////////////////////////////////
+
+type planItem struct {
+	id        int64
+	parent    int64
+	hasParent bool
+	node      *service.PlanNode
+}
+
+// planTree builds the plan from steps with parent ids in order.
+func planTree(items []planItem) *service.PlanNode {
+	nodes := make(map[int64]*service.PlanNode, len(items))
+	for _, item := range items {
+		nodes[item.id] = item.node
+	}
+
+	var roots []*service.PlanNode
+	for _, item := range items {
+		parent, ok := nodes[item.parent]
+		if !item.hasParent || !ok {
+			roots = append(roots, item.node)
+
+			continue
+		}
+
+		parent.Children = append(parent.Children, item.node)
+	}
+
+	return planRoot(roots)
+}
+
+// planRoot returns the single root or a root holding all of them.
+func planRoot(nodes []*service.PlanNode) *service.PlanNode {
+	if len(nodes) == 1 {
+		return nodes[0]
+	}
+
+	return &service.PlanNode{Operation: "QUERY PLAN", Children: nodes}
+}
+
+// joinDetail joins non empty label and value pairs like "filter: (a > 1)".
+func joinDetail(labelValues ...string) string {
+	var parts []string
+	for i := 0; i+1 < len(labelValues); i += 2 {
+		if labelValues[i+1] != "" {
+			parts = append(parts, labelValues[i]+": "+labelValues[i+1])
+		}
+	}
+
+	return strings.Join(parts, "; ")
+}
+
+func planFloat(v any) types.Null[float64] {
+	switch val := v.(type) {
+	case float64:
+		return types.NewNull(val)
+	case string:
+		if f, err := strconv.ParseFloat(val, 64); err == nil {
+			return types.NewNull(f)
+		}
+	}
+
+	return types.Null[float64]{}
+}
+
+func planString(v any) string {
+	s, _ := v.(string)
+
+	return s
+}
+
+// postgresPlan converts the JSON output of EXPLAIN (FORMAT JSON).
+func postgresPlan(text string) (*service.PlanNode, error) {
+	var plans []struct {
+		Plan map[string]any `json:"Plan"`
+	}
+
+	if err := json.Unmarshal([]byte(text), &plans); err != nil {
+		return nil, fmt.Errorf("parse plan: %w", err)
+	}
+
+	if len(plans) == 0 || plans[0].Plan == nil {
+		return nil, fmt.Errorf("plan is empty")
+	}
+
+	return postgresNode(plans[0].Plan), nil
+}
+
+func postgresNode(m map[string]any) *service.PlanNode {
+	node := &service.PlanNode{
+		Operation:  planString(m["Node Type"]),
+		Object:     planString(m["Relation Name"]),
+		Cost:       planFloat(m["Total Cost"]),
+		Rows:       planFloat(m["Plan Rows"]),
+		ActualRows: planFloat(m["Actual Rows"]),
+		ActualTime: planFloat(m["Actual Total Time"]),
+	}
+
+	var sortKey string
+	if keys, ok := m["Sort Key"].([]any); ok {
+		parts := make([]string, 0, len(keys))
+		for _, k := range keys {
+			parts = append(parts, planString(k))
+		}
+
+		sortKey = strings.Join(parts, ", ")
+	}
+
+	node.Detail = joinDetail(
+		"join", planString(m["Join Type"]),
+		"index", planString(m["Index Name"]),
+		"index cond", planString(m["Index Cond"]),
+		"hash cond", planString(m["Hash Cond"]),
+		"merge cond", planString(m["Merge Cond"]),
+		"join filter", planString(m["Join Filter"]),
+		"filter", planString(m["Filter"]),
+		"sort key", sortKey,
+	)
+
+	if children, ok := m["Plans"].([]any); ok {
+		for _, child := range children {
+			if childMap, ok := child.(map[string]any); ok {
+				node.Children = append(node.Children, postgresNode(childMap))
+			}
+		}
+	}
+
+	return node
+}
+
+// mysqlPlan converts the JSON output of EXPLAIN FORMAT=JSON, every nested object is a step named by its key.
+func mysqlPlan(text string) (*service.PlanNode, error) {
+	var doc map[string]any
+	if err := json.Unmarshal([]byte(text), &doc); err != nil {
+		return nil, fmt.Errorf("parse plan: %w", err)
+	}
+
+	queryBlock, ok := doc["query_block"].(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("plan has no query_block")
+	}
+
+	return mysqlNode("query_block", queryBlock), nil
+}
+
+func mysqlNode(key string, m map[string]any) *service.PlanNode {
+	node := &service.PlanNode{Operation: key}
+
+	if costInfo, ok := m["cost_info"].(map[string]any); ok {
+		node.Cost = planFloat(costInfo["query_cost"])
+		if !node.Cost.Valid {
+			node.Cost = planFloat(costInfo["prefix_cost"])
+		}
+	}
+
+	if key == "table" {
+		if accessType := planString(m["access_type"]); accessType != "" {
+			node.Operation = accessType
+		}
+
+		node.Object = planString(m["table_name"])
+		node.Rows = planFloat(m["rows_examined_per_scan"])
+		node.Detail = joinDetail("key", planString(m["key"]), "condition", planString(m["attached_condition"]))
+	}
+
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	slices.Sort(keys)
+
+	for _, k := range keys {
+		if k == "cost_info" {
+			continue
+		}
+
+		switch v := m[k].(type) {
+		case map[string]any:
+			node.Children = append(node.Children, mysqlNode(k, v))
+		case []any:
+			group := &service.PlanNode{Operation: k}
+			for _, item := range v {
+				itemMap, ok := item.(map[string]any)
+				if !ok {
+					continue
+				}
+
+				// nested_loop items are {"table": {...}}
+				if len(itemMap) == 1 {
+					for itemKey, itemValue := range itemMap {
+						if inner, ok := itemValue.(map[string]any); ok {
+							group.Children = append(group.Children, mysqlNode(itemKey, inner))
+						}
+					}
+
+					continue
+				}
+
+				group.Children = append(group.Children, mysqlNode(k, itemMap))
+			}
+
+			if len(group.Children) > 0 {
+				node.Children = append(node.Children, group)
+			}
+		}
+	}
+
+	return node
+}
+
+type xmlNode struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr `xml:",any,attr"`
+	Children []xmlNode  `xml:",any"`
+}
+
+func (n *xmlNode) attr(name string) string {
+	for _, a := range n.Attrs {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+
+	return ""
+}
+
+// sqlServerPlan converts a showplan XML, the root holds the plans of the statements.
+func sqlServerPlan(text string) (*service.PlanNode, error) {
+	var root xmlNode
+	if err := xml.Unmarshal([]byte(text), &root); err != nil {
+		return nil, fmt.Errorf("parse plan: %w", err)
+	}
+
+	node := &service.PlanNode{Operation: "QUERY PLAN"}
+	sqlServerChildren(&root, node)
+
+	return node, nil
+}
+
+// sqlServerChildren adds the nearest RelOp elements under n to node.
+func sqlServerChildren(n *xmlNode, node *service.PlanNode) {
+	for i := range n.Children {
+		child := &n.Children[i]
+
+		switch child.XMLName.Local {
+		case "RelOp":
+			node.Children = append(node.Children, sqlServerNode(child))
+		case "Object":
+			if node.Object == "" {
+				node.Object = strings.Trim(child.attr("Table"), "[]")
+				if index := strings.Trim(child.attr("Index"), "[]"); index != "" {
+					node.Detail = joinDetail("index", index)
+				}
+			}
+		default:
+			sqlServerChildren(child, node)
+		}
+	}
+}
+
+func sqlServerNode(relOp *xmlNode) *service.PlanNode {
+	node := &service.PlanNode{
+		Operation: relOp.attr("PhysicalOp"),
+		Cost:      planFloat(relOp.attr("EstimatedTotalSubtreeCost")),
+		Rows:      planFloat(relOp.attr("EstimateRows")),
+	}
+
+	sqlServerChildren(relOp, node)
+
+	if logicalOp := relOp.attr("LogicalOp"); logicalOp != node.Operation {
+		node.Detail = joinDetail("logical", logicalOp) + strings.TrimPrefix("; "+node.Detail, "; ")
+	}
+
+	return node
+}
@@ -0,0 +1,164 @@
+package database
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/worldline-go/saz/internal/service"
+	"github.com/worldline-go/types"
+)
+
+func TestExplain(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	_, err = db.Exec("CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT); CREATE TABLE tags (item_id INTEGER, tag TEXT)")
+	require.NoError(t, err)
+
+	d := &Database{DB: map[string]*Info{"sqlite": {DB: db, DBType: "sqlite3"}}}
+
+	result, err := d.Explain(t.Context(), "sqlite",
+		"SELECT name FROM items JOIN tags ON tags.item_id = items.id WHERE items.id = :id;", map[string]any{"id": 1})
+	require.NoError(t, err)
+	require.NotEmpty(t, result.Rows())
+
+	plan := result.(service.PlanResult).Plan()
+	require.NotNil(t, plan)
+	require.Equal(t, "QUERY PLAN", plan.Operation)
+	require.Len(t, plan.Children, 2)
+
+	_, err = d.Explain(t.Context(), "missing", "SELECT 1", nil)
+	require.ErrorIs(t, err, service.ErrNotExists)
+}
+
+func TestPostgresPlan(t *testing.T) {
+	plan, err := postgresPlan(`[{"Plan": {"Node Type": "Hash Join", "Join Type": "Inner", "Total Cost": 38.25, "Plan Rows": 10,
+		"Actual Rows": 2, "Actual Total Time": 0.05, "Hash Cond": "(t.item_id = i.id)",
+		"Plans": [
+			{"Node Type": "Seq Scan", "Relation Name": "tags", "Total Cost": 22.7, "Plan Rows": 1270, "Actual Rows": 4, "Actual Total Time": 0.01},
+			{"Node Type": "Index Scan", "Relation Name": "items", "Index Name": "items_pkey", "Index Cond": "(id = 1)", "Total Cost": 8.17, "Plan Rows": 1}
+		]}, "Execution Time": 0.1}]`)
+	require.NoError(t, err)
+
+	require.Equal(t, &service.PlanNode{
+		Operation:  "Hash Join",
+		Detail:     "join: Inner; hash cond: (t.item_id = i.id)",
+		Cost:       types.NewNull(38.25),
+		Rows:       types.NewNull(10.0),
+		ActualRows: types.NewNull(2.0),
+		ActualTime: types.NewNull(0.05),
+		Children: []*service.PlanNode{
+			{
+				Operation:  "Seq Scan",
+				Object:     "tags",
+				Cost:       types.NewNull(22.7),
+				Rows:       types.NewNull(1270.0),
+				ActualRows: types.NewNull(4.0),
+				ActualTime: types.NewNull(0.01),
+			},
+			{
+				Operation: "Index Scan",
+				Object:    "items",
+				Detail:    "index: items_pkey; index cond: (id = 1)",
+				Cost:      types.NewNull(8.17),
+				Rows:      types.NewNull(1.0),
+			},
+		},
+	}, plan)
+}
+
+func TestMySQLPlan(t *testing.T) {
+	plan, err := mysqlPlan(`{"query_block": {"select_id": 1, "cost_info": {"query_cost": "1.40"},
+		"nested_loop": [
+			{"table": {"table_name": "i", "access_type": "const", "key": "PRIMARY", "rows_examined_per_scan": 1, "cost_info": {"prefix_cost": "0.00"}}},
+			{"table": {"table_name": "t", "access_type": "ALL", "rows_examined_per_scan": 4, "attached_condition": "(t.item_id = 1)", "cost_info": {"prefix_cost": "1.40"}}}
+		]}}`)
+	require.NoError(t, err)
+
+	require.Equal(t, &service.PlanNode{
+		Operation: "query_block",
+		Cost:      types.NewNull(1.4),
+		Children: []*service.PlanNode{
+			{
+				Operation: "nested_loop",
+				Children: []*service.PlanNode{
+					{Operation: "const", Object: "i", Detail: "key: PRIMARY", Cost: types.NewNull(0.0), Rows: types.NewNull(1.0)},
+					{Operation: "ALL", Object: "t", Detail: "condition: (t.item_id = 1)", Cost: types.NewNull(1.4), Rows: types.NewNull(4.0)},
+				},
+			},
+		},
+	}, plan)
+}
+
+func TestSQLServerPlan(t *testing.T) {
+	plan, err := sqlServerPlan(`<ShowPlanXML xmlns="http://schemas.microsoft.com/sqlserver/2004/07/showplan" Version="1.6">
+<BatchSequence><Batch><Statements><StmtSimple StatementText="SELECT name FROM items WHERE id = 1">
+<QueryPlan>
+  <RelOp NodeId="0" PhysicalOp="Nested Loops" LogicalOp="Inner Join" EstimateRows="1" EstimatedTotalSubtreeCost="0.0065">
+    <NestedLoops>
+      <RelOp NodeId="1" PhysicalOp="Clustered Index Seek" LogicalOp="Clustered Index Seek" EstimateRows="1" EstimatedTotalSubtreeCost="0.0032">
+        <IndexScan><Object Database="[app]" Schema="[dbo]" Table="[items]" Index="[PK_items]"/></IndexScan>
+      </RelOp>
+      <RelOp NodeId="2" PhysicalOp="Table Scan" LogicalOp="Table Scan" EstimateRows="4" EstimatedTotalSubtreeCost="0.0033">
+        <TableScan><Object Database="[app]" Schema="[dbo]" Table="[tags]"/></TableScan>
+      </RelOp>
+    </NestedLoops>
+  </RelOp>
+</QueryPlan>
+</StmtSimple></Statements></Batch></BatchSequence></ShowPlanXML>`)
+	require.NoError(t, err)
+
+	require.Equal(t, &service.PlanNode{
+		Operation: "QUERY PLAN",
+		Children: []*service.PlanNode{
+			{
+				Operation: "Nested Loops",
+				Detail:    "logical: Inner Join",
+				Cost:      types.NewNull(0.0065),
+				Rows:      types.NewNull(1.0),
+				Children: []*service.PlanNode{
+					{Operation: "Clustered Index Seek", Object: "items", Detail: "index: PK_items", Cost: types.NewNull(0.0032), Rows: types.NewNull(1.0)},
+					{Operation: "Table Scan", Object: "tags", Cost: types.NewNull(0.0033), Rows: types.NewNull(4.0)},
+				},
+			},
+		},
+	}, plan)
+}
+
+func TestDiscardConn(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	conn, err := db.Conn(t.Context())
+	require.NoError(t, err)
+
+	discardConn(conn)
+	require.ErrorIs(t, conn.Close(), sql.ErrConnDone)
+
+	// connection is not returned to the pool
+	require.Zero(t, db.Stats().OpenConnections)
+}
+
+func TestPlanTree(t *testing.T) {
+	// oracle plan table rows
+	items := []planItem{
+		{id: 0, node: &service.PlanNode{Operation: "SELECT STATEMENT"}},
+		{id: 1, parent: 0, hasParent: true, node: &service.PlanNode{Operation: "NESTED LOOPS"}},
+		{id: 2, parent: 1, hasParent: true, node: &service.PlanNode{Operation: "TABLE ACCESS BY INDEX ROWID", Object: "ITEMS"}},
+		{id: 3, parent: 2, hasParent: true, node: &service.PlanNode{Operation: "INDEX UNIQUE SCAN", Object: "ITEMS_PK"}},
+		{id: 4, parent: 1, hasParent: true, node: &service.PlanNode{Operation: "TABLE ACCESS FULL", Object: "TAGS"}},
+	}
+
+	plan := planTree(items)
+	require.Equal(t, "SELECT STATEMENT", plan.Operation)
+	require.Len(t, plan.Children, 1)
+
+	loops := plan.Children[0]
+	require.Len(t, loops.Children, 2)
+	require.Equal(t, "ITEMS", loops.Children[0].Object)
+	require.Equal(t, "ITEMS_PK", loops.Children[0].Children[0].Object)
+	require.Equal(t, "TAGS", loops.Children[1].Object)
+}
@@ -5,32 +5,39 @@ import (
 	"database/sql"
 	"fmt"
 	"iter"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/godror/godror"
 	"github.com/oklog/ulid/v2"
 	"github.com/spf13/cast"
 	"github.com/worldline-go/saz/internal/service"
 )
 
-func (d *Database) DatabaseList() []string {
-	dbList := make([]string, 0, len(d.DB))
-	for name := range d.DB {
-		dbList = append(dbList, name)
+func (d *Database) Exec(ctx context.Context, name, query string, params map[string]any) (service.Result, error) {
+	dbConn, err := d.conn(ctx, name)
+	if err != nil {
+		return nil, err
 	}
+	defer dbConn.release()
 
-	return dbList
-}
+	if err := checkReadOnly(name, dbConn, query); err != nil {
+		return nil, err
+	}
 
-func (d *Database) Exec(ctx context.Context, name, query string) (service.Result, error) {
-	dbConn, ok := d.DB[name]
-	if !ok {
-		return nil, fmt.Errorf("database %s; %w", name, service.ErrNotExists)
+	db, release, err := session(ctx, dbConn, dbConn.DB)
+	if err != nil {
+		return nil, fmt.Errorf("database %s: %w", name, err)
 	}
+	defer release()
+
+	query, args := BindNamed(dbConn.DBType, query, params)
 
 	start := time.Now()
 
-	result, err := dbConn.DB.ExecContext(ctx, query)
+	result, err := db.ExecContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("execute query on database %s: %w", name, err)
 	}
@@ -47,91 +54,277 @@ func (d *Database) Exec(ctx context.Context, name, query string) (service.Result
 	}, nil
 }
 
-func (d *Database) Query(ctx context.Context, name, query string, limit int64) (service.Result, error) {
-	dbConn, ok := d.DB[name]
-	if !ok {
-		return nil, fmt.Errorf("database %s; %w", name, service.ErrNotExists)
+func (d *Database) Query(ctx context.Context, name, query string, params map[string]any, limit int64, typed bool, snapshot service.Snapshot) (service.Result, error) {
+	dbConn, err := d.conn(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	defer dbConn.release()
+
+	if err := checkReadOnly(name, dbConn, query); err != nil {
+		return nil, err
+	}
+
+	reader, release, err := d.reader(ctx, name, dbConn, snapshot)
+	if err != nil {
+		return nil, err
 	}
+	defer release()
+
+	query, args := BindNamed(dbConn.DBType, query, params)
 
 	start := time.Now()
-	rows := [][]any{}
-	rowsIter, err := dbConn.DB.QueryContext(ctx, query)
+	rowsIter, err := reader.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("run query on database %s: %w", name, err)
 	}
 	defer rowsIter.Close()
 
+	result, err := readResultSet(rowsIter, limit, typed)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := rowsIter.Err(); err != nil {
+		return nil, fmt.Errorf("iterate rows: %w", err)
+	}
+
+	result.duration = time.Since(start)
+
+	return result, nil
+}
+
+// readResultSet reads the current result set of rows up to limit rows, limit 0 reads all.
+func readResultSet(rowsIter *sql.Rows, limit int64, typed bool) (*Result, error) {
+	rows := [][]any{}
+
 	columns, err := rowsIter.Columns()
 	if err != nil {
 		return nil, fmt.Errorf("get columns: %w", err)
 	}
 
+	var columnInfos []service.ColumnInfo
+	if typed {
+		columnTypes, err := rowsIter.ColumnTypes()
+		if err != nil {
+			return nil, fmt.Errorf("get column types: %w", err)
+		}
+
+		columnInfos = ColumnInfos(columnTypes)
+	}
+
 	for rowsIter.Next() {
 		values, err := ScanSlice(len(columns), rowsIter)
 		if err != nil {
 			return nil, fmt.Errorf("scan row: %w", err)
 		}
 
-		valuesStr := make([]any, 0, len(values))
-		for _, v := range values {
-			valuesStr = append(valuesStr, cast.ToString(v))
-		}
-
-		rows = append(rows, valuesStr)
+		rows = append(rows, resultRow(values, typed, columnInfos))
 
 		limit--
 		if limit == 0 {
 			break
 		}
 	}
-	if err := rowsIter.Err(); err != nil {
-		return nil, fmt.Errorf("iterate rows: %w", err)
-	}
 
 	return &Result{
-		columns:  columns,
-		rows:     rows,
-		duration: time.Since(start),
+		columns:     columns,
+		columnTypes: columnInfos,
+		rows:        rows,
+	}, nil
+}
+
+// resultRow converts scanned values to typed values or strings.
+func resultRow(values []any, typed bool, columnInfos []service.ColumnInfo) []any {
+	if typed {
+		for i := range values {
+			values[i] = typedValue(values[i], columnInfos[i])
+		}
+
+		return values
+	}
+
+	valuesStr := make([]any, 0, len(values))
+	for _, v := range values {
+		valuesStr = append(valuesStr, cast.ToString(v))
+	}
+
+	return valuesStr
+}
+
+// QueryStream runs the query and returns rows while they are scanned, values are the same as Query.
+// Rows must be iterated to close the query, stopping the iteration closes it early.
+func (d *Database) QueryStream(ctx context.Context, name, query string, params map[string]any, limit int64, typed bool, snapshot service.Snapshot) (*service.RowStream, error) {
+	dbConn, err := d.conn(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	defer dbConn.release()
+
+	if err := checkReadOnly(name, dbConn, query); err != nil {
+		return nil, err
+	}
+
+	reader, release, err := d.reader(ctx, name, dbConn, snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	query, args := BindNamed(dbConn.DBType, query, params)
+
+	rowsIter, err := reader.QueryContext(ctx, query, args...)
+	if err != nil {
+		release()
+
+		return nil, fmt.Errorf("run query on database %s: %w", name, err)
+	}
+
+	closeRows := sync.OnceValue(func() error {
+		defer release()
+
+		return rowsIter.Close()
+	})
+
+	columns, err := rowsIter.Columns()
+	if err != nil {
+		closeRows()
+
+		return nil, fmt.Errorf("get columns: %w", err)
+	}
+
+	var columnInfos []service.ColumnInfo
+	if typed {
+		columnTypes, err := rowsIter.ColumnTypes()
+		if err != nil {
+			closeRows()
+
+			return nil, fmt.Errorf("get column types: %w", err)
+		}
+
+		columnInfos = ColumnInfos(columnTypes)
+	}
+
+	return &service.RowStream{
+		Columns:     columns,
+		ColumnTypes: columnInfos,
+		Rows: func(yield func([]any, error) bool) {
+			defer closeRows()
+
+			for count := int64(0); limit <= 0 || count < limit; count++ {
+				if !rowsIter.Next() {
+					break
+				}
+
+				values, err := ScanSlice(len(columns), rowsIter)
+				if err != nil {
+					_ = !yield(nil, fmt.Errorf("scan row: %w", err))
+					return
+				}
+
+				if !yield(resultRow(values, typed, columnInfos), nil) {
+					return
+				}
+			}
+
+			if err := rowsIter.Err(); err != nil {
+				_ = !yield(nil, fmt.Errorf("iterate rows: %w", err))
+			}
+		},
 	}, nil
 }
 
 // /////////////////////////////////////////////
 
-func (d *Database) IterGet(ctx context.Context, name, query string, mapType service.MapType) ([]string, iter.Seq2[[]any, error], error) {
-	dbConn, ok := d.DB[name]
-	if !ok {
-		return nil, nil, fmt.Errorf("database %s; %w", name, service.ErrNotExists)
+func (d *Database) IterGet(ctx context.Context, name, query string, params map[string]any, mapType service.MapType, lob service.LOB, snapshot service.Snapshot) ([]string, iter.Seq2[[]any, error], error) {
+	dbConn, err := d.conn(ctx, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer dbConn.release()
+
+	if err := checkReadOnly(name, dbConn, query); err != nil {
+		return nil, nil, err
 	}
 
-	rowsIter, err := dbConn.DB.QueryContext(ctx, query)
+	var args []any
+	if lob.Enabled && dbConn.DBType == "godror" {
+		// stream oracle LOBs instead of materializing them in the driver
+		args = append(args, godror.LobAsReader())
+	}
+
+	query, bindArgs := BindNamed(dbConn.DBType, query, params)
+	args = append(args, bindArgs...)
+
+	reader, release, err := d.reader(ctx, name, dbConn, snapshot)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rowsIter, err := reader.QueryContext(ctx, query, args...)
 	if err != nil {
+		release()
+
 		return nil, nil, fmt.Errorf("run query on database %s: %w", name, err)
 	}
 
+	// release the snapshot after rows are closed, iterator can be run again to drain it
+	closeRows := sync.OnceValue(func() error {
+		defer release()
+
+		return rowsIter.Close()
+	})
+
 	columns, err := rowsIter.Columns()
 	if err != nil {
+		closeRows()
+
 		return nil, nil, fmt.Errorf("get columns: %w", err)
 	}
 
 	var dynamicSlice []any
-	var columnsIndex map[string]int
+	var plan *mappingPlan
+	var lobColumns []int
+	// lobMapped has LOB columns with a type in the column map, they get the type after the LOB is read
+	lobMapped := make(map[int]*sql.ColumnType)
 
-	if mapType.Enabled {
+	if mapType.Enabled || lob.Enabled {
 		columnTypes, err := rowsIter.ColumnTypes()
 		if err != nil {
+			closeRows()
+
 			return nil, nil, fmt.Errorf("get column types: %w", err)
 		}
 
-		dynamicSlice = GenerateSlice(columnTypes, mapType)
+		if lob.Enabled {
+			for i, col := range columnTypes {
+				if IsLOBType(col.DatabaseTypeName()) {
+					lobColumns = append(lobColumns, i)
+				}
+			}
+		}
+
+		if mapType.Enabled {
+			dynamicSlice = GenerateSlice(columnTypes, mapType)
+			// LOB values are resolved after scan
+			for _, i := range lobColumns {
+				if _, ok := mapType.Column[columns[i]]; ok {
+					lobMapped[i] = columnTypes[i]
+				}
+
+				dynamicSlice[i] = new(any)
+			}
 
-		columnsIndex = make(map[string]int, len(columns))
-		for i, col := range columns {
-			columnsIndex[col] = i
+			plan, err = compileMapping(dbConn.DBType, columns, mapType, d.MaskSalt)
+			if err != nil {
+				closeRows()
+
+				return nil, nil, fmt.Errorf("compile mapping: %w", err)
+			}
 		}
 	}
 
 	return columns, func(yield func([]any, error) bool) {
-		defer rowsIter.Close()
+		defer closeRows()
 
 		for rowsIter.Next() {
 			var sliceRow []any
@@ -141,11 +334,6 @@ func (d *Database) IterGet(ctx context.Context, name, query string, mapType serv
 					_ = !yield(nil, fmt.Errorf("scan row: %w", err))
 					return
 				}
-
-				if err := Map(columnsIndex, mapType, sliceRow); err != nil {
-					_ = !yield(nil, fmt.Errorf("map struct to map: %w", err))
-					return
-				}
 			} else {
 				var err error
 				sliceRow, err = ScanSlice(len(columns), rowsIter)
@@ -155,6 +343,36 @@ func (d *Database) IterGet(ctx context.Context, name, query string, mapType serv
 				}
 			}
 
+			for _, i := range lobColumns {
+				value := sliceRow[i]
+				if _, ok := lobMapped[i]; ok && mapType.Column[columns[i]].Type == "string" {
+					value = TextLOB(value)
+				}
+
+				value, err := ReadLOB(value, lob)
+				if err != nil {
+					_ = !yield(nil, fmt.Errorf("read column %s: %w", columns[i], err))
+					return
+				}
+
+				if col, ok := lobMapped[i]; ok {
+					value, err = ScanLOB(GetType(col, mapType), value)
+					if err != nil {
+						_ = !yield(nil, fmt.Errorf("read column %s: %w", columns[i], err))
+						return
+					}
+				}
+
+				sliceRow[i] = value
+			}
+
+			if mapType.Enabled {
+				if err := Map(plan, sliceRow); err != nil {
+					_ = !yield(nil, fmt.Errorf("map struct to map: %w", err))
+					return
+				}
+			}
+
 			if !yield(sliceRow, nil) {
 				return
 			}
@@ -170,10 +388,58 @@ func (d *Database) IterGet(ctx context.Context, name, query string, mapType serv
 	}, nil
 }
 
-func (d *Database) IterSet(ctx context.Context, name, table string, wipe bool, skipError service.SkipError, mapType service.MapType, batchCount int, columns []string, rows iter.Seq2[[]any, error]) (service.Result, error) {
-	dbConn, ok := d.DB[name]
-	if !ok {
-		return nil, fmt.Errorf("database %s; %w", name, service.ErrNotExists)
+// IterMap applies the destination mapping of mapType to every row of rows read from the database name.
+// Rows are copied before mapping so the same source row can be shared with other destinations.
+// Strings kept as read by the source for another destination's encoding are sanitized here when not decoded.
+func (d *Database) IterMap(name string, columns []string, mapType service.MapType, rows iter.Seq2[[]any, error]) iter.Seq2[[]any, error] {
+	if !mapType.Enabled || len(mapType.Destination) == 0 {
+		return rows
+	}
+
+	var dbType string
+	if dbConn, ok := d.info(name); ok {
+		dbType = dbConn.DBType
+	}
+
+	plan, err := compileMapping(dbType, columns, mapType, d.MaskSalt)
+
+	return func(yield func([]any, error) bool) {
+		if err != nil {
+			_ = !yield(nil, fmt.Errorf("compile mapping: %w", err))
+			return
+		}
+
+		for row, err := range rows {
+			if err != nil || len(row) == 0 {
+				if !yield(row, err) {
+					return
+				}
+
+				continue
+			}
+
+			sliceRow := slices.Clone(row)
+			if err := Map(plan, sliceRow); err != nil {
+				_ = !yield(nil, fmt.Errorf("map destination: %w", err))
+				return
+			}
+
+			if !yield(sliceRow, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (d *Database) IterSet(ctx context.Context, name, table string, wipe bool, skipError service.SkipError, mapType service.MapType, lob service.LOB, batchCount int, columns []string, rows iter.Seq2[[]any, error]) (service.Result, error) {
+	dbConn, err := d.conn(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	defer dbConn.release()
+
+	if dbConn.ReadOnly {
+		return nil, fmt.Errorf("database %s is read only, it can't be a transfer destination; %w", name, service.ErrForbidden)
 	}
 
 	if len(table) == 0 || strings.ContainsAny(table, " \t\n\r") {
@@ -261,6 +527,10 @@ func (d *Database) IterSet(ctx context.Context, name, table string, wipe bool, s
 			row = batchHolder.Rows()
 		}
 
+		if lob.Enabled {
+			row = BindLOB(dbConn.DBType, row)
+		}
+
 		if skipError.Enabled {
 			tx.ExecContext(ctx, "SAVEPOINT "+savePoint)
 		}
@@ -26,6 +26,7 @@ func (s *DatabaseSuite) SetupSuite() {
 		DB: map[string]*Info{
 			"postgres": {
 				DB:          s.container.Sql(),
+				DBType:      "pgx",
 				PlaceHolder: PlaceHolder("pgx"),
 			},
 		},
@@ -65,10 +66,10 @@ func (s *DatabaseSuite) TestCopyEventsEqualCounts() {
 	_, err := s.container.Sql().ExecContext(s.T().Context(), batchQuery, args...)
 	require.NoError(s.T(), err)
 
-	columns, rows, err := s.Database.IterGet(s.T().Context(), "postgres", "select * from events", service.MapType{})
+	columns, rows, err := s.Database.IterGet(s.T().Context(), "postgres", "select * from events", nil, service.MapType{}, service.LOB{}, service.Snapshot{})
 	require.NoError(s.T(), err, "iterGet failed")
 
-	result, err := s.Database.IterSet(s.T().Context(), "postgres", "events_copy", true, service.SkipError{}, service.MapType{}, 2, columns, rows)
+	result, err := s.Database.IterSet(s.T().Context(), "postgres", "events_copy", true, service.SkipError{}, service.MapType{}, service.LOB{}, 2, columns, rows)
 	require.NoError(s.T(), err, "iterSet failed")
 	require.NotNil(s.T(), result)
 
@@ -93,10 +94,10 @@ func (s *DatabaseSuite) TestCopyEventsDiffCounts() {
 	_, err := s.container.Sql().ExecContext(s.T().Context(), batchQuery, args...)
 	require.NoError(s.T(), err)
 
-	columns, rows, err := s.Database.IterGet(s.T().Context(), "postgres", "select * from events", service.MapType{})
+	columns, rows, err := s.Database.IterGet(s.T().Context(), "postgres", "select * from events", nil, service.MapType{}, service.LOB{}, service.Snapshot{})
 	require.NoError(s.T(), err, "iterGet failed")
 
-	result, err := s.Database.IterSet(s.T().Context(), "postgres", "events_copy", true, service.SkipError{}, service.MapType{}, 3, columns, rows)
+	result, err := s.Database.IterSet(s.T().Context(), "postgres", "events_copy", true, service.SkipError{}, service.MapType{}, service.LOB{}, 3, columns, rows)
 	require.NoError(s.T(), err, "iterSet failed")
 	require.NotNil(s.T(), result)
 
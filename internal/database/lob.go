@@ -0,0 +1,173 @@
+package database
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/godror/godror"
+	"github.com/worldline-go/saz/internal/service"
+)
+
+// lobInlineLimit is the biggest value oracle binds without a LOB locator.
+const lobInlineLimit = 32767
+
+var ErrLOBTooLarge = errors.New("lob value exceeds max size")
+
+var lobTypes = map[string]struct{}{
+	"BLOB":       {},
+	"CLOB":       {},
+	"NCLOB":      {},
+	"BFILE":      {},
+	"BYTEA":      {},
+	"TINYBLOB":   {},
+	"MEDIUMBLOB": {},
+	"LONGBLOB":   {},
+	"MEDIUMTEXT": {},
+	"LONGTEXT":   {},
+	"IMAGE":      {},
+	"NTEXT":      {},
+	"VARBINARY":  {},
+}
+
+func IsLOBType(databaseTypeName string) bool {
+	_, ok := lobTypes[strings.ToUpper(databaseTypeName)]
+
+	return ok
+}
+
+// ReadLOB resolves a scanned LOB value to []byte or string.
+// Oracle LOBs are streamed so at most MaxSize bytes are kept in memory.
+func ReadLOB(v any, lob service.LOB) (any, error) {
+	switch val := v.(type) {
+	case *godror.Lob:
+		if val == nil || val.Reader == nil {
+			return nil, nil
+		}
+
+		reader := val.Reader
+		if lob.MaxSize > 0 {
+			reader = io.LimitReader(reader, lob.MaxSize+1)
+		}
+
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			return nil, fmt.Errorf("read lob: %w", err)
+		}
+
+		data, err = limitLOB(data, lob, val.IsClob)
+		if err != nil {
+			return nil, err
+		}
+
+		if val.IsClob {
+			return string(data), nil
+		}
+
+		return data, nil
+	case []byte:
+		return limitLOB(val, lob, false)
+	case string:
+		data, err := limitLOB([]byte(val), lob, true)
+		if err != nil {
+			return nil, err
+		}
+
+		return string(data), nil
+	}
+
+	return v, nil
+}
+
+// TextLOB returns the value to be read as text by ReadLOB, binary values of text columns are truncated at a character.
+func TextLOB(v any) any {
+	switch val := v.(type) {
+	case []byte:
+		return string(val)
+	case *godror.Lob:
+		if val != nil {
+			text := *val
+			text.IsClob = true
+
+			return &text
+		}
+	}
+
+	return v
+}
+
+// limitLOB checks the size limit of the value, text is truncated at the start of a UTF-8 character.
+func limitLOB(data []byte, lob service.LOB, text bool) ([]byte, error) {
+	if lob.MaxSize <= 0 || int64(len(data)) <= lob.MaxSize {
+		return data, nil
+	}
+
+	if !lob.Truncate {
+		return nil, fmt.Errorf("size limit %d; %w", lob.MaxSize, ErrLOBTooLarge)
+	}
+
+	size := lob.MaxSize
+	if text {
+		for size > 0 && !utf8.RuneStart(data[size]) {
+			size--
+		}
+	}
+
+	return data[:size], nil
+}
+
+// ScanLOB assigns the read LOB value to dest, a scan type of the column map, and returns dest.
+func ScanLOB(dest, value any) (any, error) {
+	if scanner, ok := dest.(sql.Scanner); ok {
+		if err := scanner.Scan(value); err != nil {
+			return nil, err
+		}
+
+		return dest, nil
+	}
+
+	if value == nil {
+		return dest, nil
+	}
+
+	target := reflect.ValueOf(dest).Elem()
+	source := reflect.ValueOf(value)
+	// only string and bytes are read from LOBs, conversions between them keep the content
+	if (source.Kind() != reflect.String && source.Kind() != reflect.Slice) || !source.Type().ConvertibleTo(target.Type()) {
+		return nil, fmt.Errorf("can't convert %T to %s", value, target.Type())
+	}
+
+	target.Set(source.Convert(target.Type()))
+
+	return dest, nil
+}
+
+// BindLOB wraps big values as godror.Lob so oracle streams them instead of failing on the inline limit.
+func BindLOB(dbType string, row []any) []any {
+	if dbType != "godror" {
+		return row
+	}
+
+	// row can be shared with other destinations
+	bound := slices.Clone(row)
+	for i, v := range bound {
+		switch val := v.(type) {
+		case []byte:
+			if len(val) > lobInlineLimit {
+				bound[i] = godror.Lob{Reader: bytes.NewReader(val)}
+			}
+		case string:
+			if len(val) > lobInlineLimit {
+				bound[i] = godror.Lob{Reader: strings.NewReader(val), IsClob: true}
+			}
+		}
+	}
+
+	return bound
+}
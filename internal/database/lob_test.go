@@ -0,0 +1,158 @@
+package database
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/godror/godror"
+	"github.com/stretchr/testify/require"
+	"github.com/worldline-go/saz/internal/service"
+	"github.com/worldline-go/types"
+)
+
+func TestReadLOB(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   any
+		lob     service.LOB
+		want    any
+		wantErr error
+	}{
+		{
+			name:  "clob reader",
+			value: &godror.Lob{Reader: strings.NewReader("document"), IsClob: true},
+			lob:   service.LOB{Enabled: true},
+			want:  "document",
+		},
+		{
+			name:  "blob reader truncated",
+			value: &godror.Lob{Reader: strings.NewReader("document")},
+			lob:   service.LOB{Enabled: true, MaxSize: 3, Truncate: true},
+			want:  []byte("doc"),
+		},
+		{
+			name:    "blob reader too large",
+			value:   &godror.Lob{Reader: strings.NewReader("document")},
+			lob:     service.LOB{Enabled: true, MaxSize: 3},
+			wantErr: ErrLOBTooLarge,
+		},
+		{
+			name:  "bytes in limit",
+			value: []byte("abc"),
+			lob:   service.LOB{Enabled: true, MaxSize: 3},
+			want:  []byte("abc"),
+		},
+		{
+			name:  "string truncated",
+			value: "abcdef",
+			lob:   service.LOB{Enabled: true, MaxSize: 4, Truncate: true},
+			want:  "abcd",
+		},
+		{
+			name:  "string truncated at character",
+			value: "aéb",
+			lob:   service.LOB{Enabled: true, MaxSize: 2, Truncate: true},
+			want:  "a",
+		},
+		{
+			name:  "clob reader truncated at character",
+			value: &godror.Lob{Reader: strings.NewReader("ab€"), IsClob: true},
+			lob:   service.LOB{Enabled: true, MaxSize: 4, Truncate: true},
+			want:  "ab",
+		},
+		{
+			name:  "blob truncated at byte",
+			value: []byte("aéb"),
+			lob:   service.LOB{Enabled: true, MaxSize: 2, Truncate: true},
+			want:  []byte("a\xc3"),
+		},
+		{
+			name:  "null",
+			value: nil,
+			lob:   service.LOB{Enabled: true, MaxSize: 4},
+			want:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadLOB(tt.value, tt.lob)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestScanLOB(t *testing.T) {
+	value, err := ScanLOB(new(string), []byte("text"))
+	require.NoError(t, err)
+	require.Equal(t, "text", *value.(*string))
+
+	value, err = ScanLOB(new(types.Null[string]), nil)
+	require.NoError(t, err)
+	require.False(t, value.(*types.Null[string]).Valid)
+
+	value, err = ScanLOB(new([]byte), "data")
+	require.NoError(t, err)
+	require.Equal(t, []byte("data"), *value.(*[]byte))
+
+	_, err = ScanLOB(new(int64), "1")
+	require.Error(t, err)
+}
+
+func TestIterGetLOBColumnMap(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec(`CREATE TABLE docs (id INTEGER, body BLOB)`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO docs VALUES (1, ?)`, []byte("héllo"))
+	require.NoError(t, err)
+
+	d := &Database{DB: map[string]*Info{"sqlite": {DB: db, DBType: "sqlite3"}}}
+	lob := service.LOB{Enabled: true, MaxSize: 2, Truncate: true}
+
+	read := func(mapType service.MapType) []any {
+		_, rows, err := d.IterGet(t.Context(), "sqlite", "SELECT id, body FROM docs", nil, mapType, lob, service.Snapshot{})
+		require.NoError(t, err)
+
+		for row, err := range rows {
+			require.NoError(t, err)
+
+			return row
+		}
+
+		return nil
+	}
+
+	// column map type is kept for LOB columns
+	row := read(service.MapType{Enabled: true, Column: map[string]service.ColumnType{"body": {Type: "string"}}})
+	require.Equal(t, "h", *row[1].(*string))
+
+	row = read(service.MapType{})
+	require.Equal(t, []byte("h\xc3"), row[1])
+}
+
+func TestBindLOB(t *testing.T) {
+	big := strings.Repeat("a", lobInlineLimit+1)
+	row := []any{1, "small", big, []byte(big)}
+
+	require.Equal(t, row, BindLOB("pgx", row))
+
+	bound := BindLOB("godror", row)
+	require.Equal(t, "small", bound[1])
+	require.IsType(t, godror.Lob{}, bound[2])
+	require.True(t, bound[2].(godror.Lob).IsClob)
+	require.IsType(t, godror.Lob{}, bound[3])
+	require.False(t, bound[3].(godror.Lob).IsClob)
+	require.Equal(t, big, row[2], "source row must not change")
+}
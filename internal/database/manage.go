@@ -0,0 +1,104 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+
+	"github.com/worldline-go/saz/internal/config"
+	"github.com/worldline-go/saz/internal/service"
+)
+
+// connectionConfig returns the config of the connection, the driver of the type must be registered.
+func connectionConfig(conn *service.Connection) (config.Database, error) {
+	if !slices.Contains(sql.Drivers(), conn.DBType) {
+		return config.Database{}, fmt.Errorf("unsupported db_type %q; %w", conn.DBType, service.ErrBadRequest)
+	}
+
+	queryTimeout, maxLifetime, maxIdleTime, err := conn.Durations()
+	if err != nil {
+		return config.Database{}, err
+	}
+
+	cfg := config.Database{
+		DBDatasource:    conn.Datasource,
+		DBType:          conn.DBType,
+		DBSchema:        conn.DBSchema,
+		InitStatements:  conn.InitStatements,
+		QueryTimeout:    queryTimeout,
+		ReadOnly:        conn.ReadOnly,
+		MaxOpenConns:    conn.MaxOpenConns,
+		MaxIdleConns:    conn.MaxIdleConns,
+		ConnMaxLifetime: maxLifetime,
+		ConnMaxIdleTime: maxIdleTime,
+	}
+
+	if _, err := sessionStatements(cfg); err != nil {
+		return config.Database{}, fmt.Errorf("%w; %w", err, service.ErrBadRequest)
+	}
+
+	return cfg, nil
+}
+
+// SetDatabase connects the database and swaps it in, queries running on the replaced pool end before it is closed.
+func (d *Database) SetDatabase(ctx context.Context, conn *service.Connection) error {
+	cfg, err := connectionConfig(conn)
+	if err != nil {
+		return err
+	}
+
+	dbConn := newInfo(conn.Name, cfg)
+
+	// unavailable database is added like on start
+	_ = dbConn.connect(ctx)
+
+	d.mu.Lock()
+	if d.DB == nil {
+		d.DB = make(map[string]*Info)
+	}
+
+	old := d.DB[conn.Name]
+	d.DB[conn.Name] = dbConn
+	d.mu.Unlock()
+
+	if old != nil {
+		old.retire()
+	}
+
+	return nil
+}
+
+// RemoveDatabase removes the database, its pool is closed after the queries running on it.
+func (d *Database) RemoveDatabase(name string) error {
+	d.mu.Lock()
+	dbConn, ok := d.DB[name]
+	delete(d.DB, name)
+	d.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("database %s; %w", name, service.ErrNotExists)
+	}
+
+	dbConn.retire()
+
+	return nil
+}
+
+// TestDatabase opens a connection to the database and closes it.
+func (d *Database) TestDatabase(ctx context.Context, conn *service.Connection) error {
+	cfg, err := connectionConfig(conn)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, connectTimeout)
+	defer cancel()
+
+	db, err := open(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("database %s: %w; %w", conn.Name, err, service.ErrUnavailable)
+	}
+
+	return db.Close()
+}
@@ -0,0 +1,91 @@
+package database
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/worldline-go/saz/internal/config"
+	"github.com/worldline-go/saz/internal/service"
+)
+
+func TestSetDatabase(t *testing.T) {
+	d := Connect(t.Context(), map[string]config.Database{})
+	t.Cleanup(d.Close)
+
+	conn := &service.Connection{Name: "runtime", DBType: "sqlite3", Datasource: ":memory:", QueryTimeout: "30s", ReadOnly: true}
+	require.NoError(t, d.SetDatabase(t.Context(), conn))
+	require.True(t, d.ReadOnly("runtime"))
+	require.Equal(t, "30s", d.QueryTimeout("runtime").String())
+
+	// queries run while the database is replaced
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Go(func() {
+			for range 20 {
+				// queries holding the replaced database finish on its pool
+				if _, err := d.Query(t.Context(), "runtime", "SELECT 1", nil, 0, false, service.Snapshot{}); err != nil {
+					t.Error(err)
+				}
+			}
+		})
+	}
+
+	for range 5 {
+		conn.ReadOnly = false
+		require.NoError(t, d.SetDatabase(t.Context(), conn))
+	}
+
+	wg.Wait()
+
+	require.False(t, d.ReadOnly("runtime"))
+
+	_, err := d.Query(t.Context(), "runtime", "SELECT 1", nil, 0, false, service.Snapshot{})
+	require.NoError(t, err)
+
+	require.NoError(t, d.RemoveDatabase("runtime"))
+	require.ErrorIs(t, d.RemoveDatabase("runtime"), service.ErrNotExists)
+
+	_, err = d.Query(t.Context(), "runtime", "SELECT 1", nil, 0, false, service.Snapshot{})
+	require.ErrorIs(t, err, service.ErrNotExists)
+}
+
+func TestRemoveDatabaseInUse(t *testing.T) {
+	d := Connect(t.Context(), map[string]config.Database{})
+	t.Cleanup(d.Close)
+
+	require.NoError(t, d.SetDatabase(t.Context(), &service.Connection{Name: "runtime", DBType: "sqlite3", Datasource: ":memory:"}))
+
+	dbConn, err := d.conn(t.Context(), "runtime")
+	require.NoError(t, err)
+
+	require.NoError(t, d.RemoveDatabase("runtime"))
+
+	// pool is closed after the request using it
+	require.NoError(t, dbConn.DB.PingContext(t.Context()))
+	dbConn.release()
+
+	require.Eventually(t, func() bool {
+		return dbConn.DB.PingContext(t.Context()) != nil
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestSetDatabaseInvalid(t *testing.T) {
+	d := &Database{}
+
+	err := d.SetDatabase(t.Context(), &service.Connection{Name: "x", DBType: "unknown", Datasource: "x"})
+	require.ErrorIs(t, err, service.ErrBadRequest)
+
+	err = d.SetDatabase(t.Context(), &service.Connection{Name: "x", DBType: "sqlite3", Datasource: ":memory:", QueryTimeout: "soon"})
+	require.ErrorIs(t, err, service.ErrBadRequest)
+
+	// unreachable database is added as down
+	missing := "file:" + filepath.Join(t.TempDir(), "none", "saz.db")
+	require.NoError(t, d.SetDatabase(t.Context(), &service.Connection{Name: "missing", DBType: "sqlite3", Datasource: missing}))
+	require.Equal(t, service.DatabaseDown, d.DatabaseList()[0].Status)
+
+	require.ErrorIs(t, d.TestDatabase(t.Context(), &service.Connection{Name: "missing", DBType: "sqlite3", Datasource: missing}), service.ErrUnavailable)
+	require.NoError(t, d.TestDatabase(t.Context(), &service.Connection{Name: "memory", DBType: "sqlite3", Datasource: ":memory:"}))
+}
@@ -0,0 +1,222 @@
+package database
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/spf13/cast"
+	"github.com/worldline-go/saz/internal/service"
+	"github.com/worldline-go/types"
+)
+
+var (
+	fakeFirstNames = []string{
+		"James", "Mary", "Robert", "Patricia", "John", "Jennifer", "Michael", "Linda",
+		"David", "Elizabeth", "William", "Barbara", "Richard", "Susan", "Joseph", "Jessica",
+		"Thomas", "Sarah", "Charles", "Karen", "Daniel", "Lisa", "Matthew", "Nancy",
+		"Anthony", "Sandra", "Mark", "Ashley", "Paul", "Emily", "Steven", "Donna",
+	}
+	fakeLastNames = []string{
+		"Smith", "Johnson", "Williams", "Brown", "Jones", "Garcia", "Miller", "Davis",
+		"Rodriguez", "Martinez", "Hernandez", "Lopez", "Gonzalez", "Wilson", "Anderson", "Thomas",
+		"Taylor", "Moore", "Jackson", "Martin", "Lee", "Perez", "Thompson", "White",
+		"Harris", "Sanchez", "Clark", "Ramirez", "Lewis", "Robinson", "Walker", "Young",
+	}
+)
+
+const (
+	fakeEmailDomain = "example.com"
+	// fakeIBANCountry is used when the source is not an IBAN.
+	fakeIBANCountry = "NL"
+	fakeIBANLength  = 18
+)
+
+// masker is the compiled mask of a column.
+type masker struct {
+	service.Mask
+
+	key []byte
+}
+
+func newMasker(m service.Mask, columnType, salt string) (*masker, error) {
+	switch m.Type {
+	case "":
+		return nil, nil
+	case service.MaskRedact, service.MaskNull:
+	case service.MaskHash, service.MaskName, service.MaskEmail, service.MaskIBAN, service.MaskDateShift:
+		if salt == "" {
+			return nil, fmt.Errorf("mask %s needs mask salt in config; %w", m.Type, service.ErrBadRequest)
+		}
+	default:
+		return nil, fmt.Errorf("unknown mask %q; %w", m.Type, service.ErrBadRequest)
+	}
+
+	switch m.Type {
+	case service.MaskDateShift:
+		if columnType != "date" {
+			return nil, fmt.Errorf("mask %s is only for date type; %w", m.Type, service.ErrBadRequest)
+		}
+
+		if m.Days <= 0 {
+			return nil, fmt.Errorf("mask %s needs positive days; %w", m.Type, service.ErrBadRequest)
+		}
+	case service.MaskNull:
+	default:
+		if columnType != "string" {
+			return nil, fmt.Errorf("mask %s is only for string type; %w", m.Type, service.ErrBadRequest)
+		}
+	}
+
+	if m.Length < 0 || m.Length > sha256.Size*2 {
+		return nil, fmt.Errorf("mask length %d out of range; %w", m.Length, service.ErrBadRequest)
+	}
+
+	return &masker{Mask: m, key: []byte(salt)}, nil
+}
+
+func (m *masker) sum(s string) []byte {
+	h := hmac.New(sha256.New, m.key)
+	h.Write([]byte(s))
+
+	return h.Sum(nil)
+}
+
+// mask returns the masked source value, NULL values are kept.
+// Date shift is applied after conversion with shift.
+func (m *masker) mask(v any) any {
+	if m.Type == service.MaskDateShift || isNull(v) {
+		return v
+	}
+
+	s := maskSource(v)
+
+	switch m.Type {
+	case service.MaskHash:
+		h := hex.EncodeToString(m.sum(s))
+		if m.Length > 0 {
+			h = h[:m.Length]
+		}
+
+		return h
+	case service.MaskRedact:
+		return redact(s, m.Keep)
+	case service.MaskName:
+		first, last := m.fakeName(s)
+
+		return first + " " + last
+	case service.MaskEmail:
+		first, last := m.fakeName(s)
+		n := binary.BigEndian.Uint16(m.sum(s)[4:6]) % 1000
+
+		return strings.ToLower(first) + "." + strings.ToLower(last) + strconv.Itoa(int(n)) + "@" + fakeEmailDomain
+	case service.MaskIBAN:
+		return m.fakeIBAN(s)
+	}
+
+	return v
+}
+
+// shift moves t by a deterministic number of days in [-Days, Days].
+func (m *masker) shift(t time.Time) time.Time {
+	n := binary.BigEndian.Uint64(m.sum(t.UTC().Format(time.RFC3339Nano)))
+	days := int(n%uint64(2*m.Days+1)) - m.Days
+
+	return t.AddDate(0, 0, days)
+}
+
+func (m *masker) fakeName(s string) (string, string) {
+	sum := m.sum(s)
+
+	return fakeFirstNames[int(binary.BigEndian.Uint16(sum[0:2]))%len(fakeFirstNames)],
+		fakeLastNames[int(binary.BigEndian.Uint16(sum[2:4]))%len(fakeLastNames)]
+}
+
+// fakeIBAN keeps country and length of the source IBAN and generates a BBAN with valid check digits.
+func (m *masker) fakeIBAN(s string) string {
+	country, length := fakeIBANCountry, fakeIBANLength
+
+	compact := strings.ToUpper(strings.ReplaceAll(s, " ", ""))
+	if len(compact) >= 15 && len(compact) <= 34 &&
+		isASCIIUpper(compact[0]) && isASCIIUpper(compact[1]) {
+		country, length = compact[:2], len(compact)
+	}
+
+	var bban strings.Builder
+
+	for sum := m.sum(s); bban.Len() < length-4; sum = m.sum(string(sum)) {
+		for _, b := range sum {
+			if bban.Len() == length-4 {
+				break
+			}
+
+			bban.WriteByte('0' + b%10)
+		}
+	}
+
+	return country + ibanCheckDigits(country, bban.String()) + bban.String()
+}
+
+// ibanCheckDigits calculates ISO 13616 check digits with mod 97.
+func ibanCheckDigits(country, bban string) string {
+	var digits strings.Builder
+
+	for _, r := range bban + country + "00" {
+		if r >= 'A' && r <= 'Z' {
+			digits.WriteString(strconv.Itoa(int(r-'A') + 10))
+
+			continue
+		}
+
+		digits.WriteRune(r)
+	}
+
+	n, _ := new(big.Int).SetString(digits.String(), 10)
+	mod := new(big.Int).Mod(n, big.NewInt(97)).Int64()
+
+	return fmt.Sprintf("%02d", 98-mod)
+}
+
+func isASCIIUpper(b byte) bool {
+	return b >= 'A' && b <= 'Z'
+}
+
+// redact replaces letters and digits with * keeping the last keep characters and separators.
+func redact(s string, keep int) string {
+	runes := []rune(s)
+	for i := range max(len(runes)-keep, 0) {
+		if unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) {
+			runes[i] = '*'
+		}
+	}
+
+	return string(runes)
+}
+
+func maskSource(v any) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case []byte:
+		return string(val)
+	case types.Null[string]:
+		return val.V
+	case UUID:
+		return val.V
+	case time.Time:
+		return val.UTC().Format(time.RFC3339Nano)
+	case types.Time:
+		return val.UTC().Format(time.RFC3339Nano)
+	case fmt.Stringer:
+		return val.String()
+	}
+
+	return cast.ToString(v)
+}
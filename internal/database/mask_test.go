@@ -0,0 +1,116 @@
+package database
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/worldline-go/saz/internal/service"
+	"github.com/worldline-go/types"
+)
+
+func TestMask(t *testing.T) {
+	mapColumn := func(t *testing.T, salt string, column service.ColumnTypeTemplate, value any) any {
+		t.Helper()
+
+		plan, err := compileMapping("", []string{"col"}, service.MapType{
+			Enabled:     true,
+			Destination: map[string]service.ColumnTypeTemplate{"col": column},
+		}, salt)
+		require.NoError(t, err)
+
+		row := []any{value}
+		require.NoError(t, plan.apply(row))
+
+		return row[0]
+	}
+
+	t.Run("hash", func(t *testing.T) {
+		column := service.ColumnTypeTemplate{Type: "string", Mask: service.Mask{Type: service.MaskHash, Length: 16}}
+
+		v := mapColumn(t, "secret", column, "john@doe.com")
+		require.Len(t, v, 16)
+		require.Equal(t, v, mapColumn(t, "secret", column, []byte("john@doe.com")))
+		require.NotEqual(t, v, mapColumn(t, "other", column, "john@doe.com"))
+		require.NotEqual(t, v, mapColumn(t, "secret", column, "jane@doe.com"))
+	})
+
+	t.Run("redact", func(t *testing.T) {
+		column := service.ColumnTypeTemplate{Type: "string", Mask: service.Mask{Type: service.MaskRedact, Keep: 4}}
+
+		require.Equal(t, "****-****-****-1234", mapColumn(t, "", column, "4111-1111-1111-1234"))
+		require.Equal(t, "ab", mapColumn(t, "", column, "ab"))
+	})
+
+	t.Run("null", func(t *testing.T) {
+		column := service.ColumnTypeTemplate{Type: "number", Mask: service.Mask{Type: service.MaskNull}}
+
+		require.Nil(t, mapColumn(t, "", column, "1.5"))
+	})
+
+	t.Run("null kept", func(t *testing.T) {
+		column := service.ColumnTypeTemplate{Type: "string", Nullable: true, Mask: service.Mask{Type: service.MaskName}}
+
+		require.Equal(t, types.NewNullWithValid("", false), mapColumn(t, "secret", column, types.NewNullWithValid("", false)))
+	})
+
+	t.Run("name and email", func(t *testing.T) {
+		name := mapColumn(t, "secret", service.ColumnTypeTemplate{Type: "string", Mask: service.Mask{Type: service.MaskName}}, "John Doe").(string)
+		require.Len(t, strings.Fields(name), 2)
+
+		email := mapColumn(t, "secret", service.ColumnTypeTemplate{Type: "string", Mask: service.Mask{Type: service.MaskEmail}}, "John Doe").(string)
+		require.Regexp(t, regexp.MustCompile(`^[a-z]+\.[a-z]+\d+@example\.com$`), email)
+		require.True(t, strings.HasPrefix(email, strings.ToLower(strings.ReplaceAll(name, " ", "."))))
+	})
+
+	t.Run("iban", func(t *testing.T) {
+		column := service.ColumnTypeTemplate{Type: "string", Mask: service.Mask{Type: service.MaskIBAN}}
+
+		iban := mapColumn(t, "secret", column, "DE89 3704 0044 0532 0130 00").(string)
+		require.Len(t, iban, 22)
+		require.True(t, strings.HasPrefix(iban, "DE"))
+		require.NotEqual(t, "DE89370400440532013000", iban)
+		require.Equal(t, iban[2:4], ibanCheckDigits("DE", iban[4:]))
+
+		require.Len(t, mapColumn(t, "secret", column, "not an iban"), fakeIBANLength)
+
+		// known valid IBAN
+		require.Equal(t, "89", ibanCheckDigits("DE", "370400440532013000"))
+	})
+
+	t.Run("date shift", func(t *testing.T) {
+		column := service.ColumnTypeTemplate{Type: "date", Mask: service.Mask{Type: service.MaskDateShift, Days: 30}}
+		value := time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC)
+
+		v := mapColumn(t, "secret", column, value).(types.Time)
+		require.Equal(t, v, mapColumn(t, "secret", column, value))
+		require.LessOrEqual(t, v.Sub(value).Abs(), 30*24*time.Hour)
+		require.Equal(t, value.Hour(), v.Hour())
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, column := range []service.ColumnTypeTemplate{
+			{Type: "string", Mask: service.Mask{Type: service.MaskHash, Length: 65}},
+			{Type: "string", Mask: service.Mask{Type: "scramble"}},
+			{Type: "number", Mask: service.Mask{Type: service.MaskRedact}},
+			{Type: "string", Mask: service.Mask{Type: service.MaskDateShift, Days: 1}},
+			{Type: "date", Mask: service.Mask{Type: service.MaskDateShift}},
+		} {
+			_, err := compileMapping("", []string{"col"}, service.MapType{
+				Enabled:     true,
+				Destination: map[string]service.ColumnTypeTemplate{"col": column},
+			}, "secret")
+			require.Error(t, err, column.Mask.Type)
+		}
+
+		_, err := compileMapping("", []string{"col"}, service.MapType{
+			Enabled: true,
+			Destination: map[string]service.ColumnTypeTemplate{
+				"col": {Type: "string", Mask: service.Mask{Type: service.MaskHash}},
+			},
+		}, "")
+		require.ErrorIs(t, err, service.ErrBadRequest)
+	})
+}
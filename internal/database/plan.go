@@ -0,0 +1,347 @@
+package database
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/worldline-go/saz/internal/render"
+	"github.com/worldline-go/saz/internal/service"
+	"github.com/worldline-go/types"
+)
+
+var ErrNullValue = errors.New("null value not allowed")
+
+// columnMapping is the destination mapping of a column with its template and decoder prepared.
+type columnMapping struct {
+	service.ColumnTypeTemplate
+
+	name     string
+	index    int
+	template *render.Template
+	decoder  func(data []byte) (string, error)
+	convert  func(c *columnMapping, v any) (any, error)
+	// nullValue replaces NULL values for default and empty null policies.
+	nullValue any
+	mask      *masker
+	// mixedEndian is set when binary uuids of the source are in MSSQL byte order.
+	mixedEndian bool
+}
+
+func (c *columnMapping) render(v any) ([]byte, error) {
+	return c.template.ExecuteWithData(v)
+}
+
+func (c *columnMapping) renderValue(v string) (string, error) {
+	if !c.Template.Enabled {
+		return v, nil
+	}
+
+	vRendered, err := c.render(v)
+	if err != nil {
+		return "", err
+	}
+
+	return string(vRendered), nil
+}
+
+func (c *columnMapping) decode(data []byte) (string, error) {
+	if c.decoder == nil {
+		return string(data), nil
+	}
+
+	return c.decoder(data)
+}
+
+// decodeString decodes the bytes of a string scanned by the driver in the source encoding.
+func (c *columnMapping) decodeString(s string) (string, error) {
+	if c.decoder == nil {
+		return s, nil
+	}
+
+	return c.decoder([]byte(s))
+}
+
+// mappingPlan is the destination mapping compiled once for a transfer and applied to every row.
+type mappingPlan struct {
+	columns []*columnMapping
+	// decoded marks the source columns decoded from an encoding, their values are kept as read before mapping.
+	decoded []bool
+}
+
+// compileMapping parses templates, resolves column indexes and selects converters of the destination mapping.
+// dbType is the source of the rows, salt keys the masks of columns. Returns nil plan when there is nothing to map.
+// An entry with an encoding but without a type is not mapped, it only keeps the values as read for a later mapping.
+func compileMapping(dbType string, columns []string, mapType service.MapType, salt string) (*mappingPlan, error) {
+	if !mapType.Enabled || len(mapType.Destination) == 0 {
+		return nil, nil
+	}
+
+	columnsIndex := make(map[string]int, len(columns))
+	for i, col := range columns {
+		columnsIndex[col] = i
+	}
+
+	plan := &mappingPlan{decoded: make([]bool, len(columns))}
+	for name, colType := range mapType.Destination {
+		idx, ok := columnsIndex[name]
+		if !ok {
+			continue
+		}
+
+		c := &columnMapping{
+			ColumnTypeTemplate: colType,
+			name:               name,
+			index:              idx,
+			mixedEndian:        mixedEndianUUID(dbType),
+		}
+
+		convert, ok := converters[colType.Type]
+		if !ok {
+			if colType.Type == "" && colType.Encoding.Enabled {
+				plan.decoded[idx] = true
+			}
+
+			continue
+		}
+
+		c.convert = convert
+
+		if colType.Template.Enabled {
+			tpl, err := render.Parse(colType.Template.Value)
+			if err != nil {
+				return nil, fmt.Errorf("parse template of column %s: %w", name, err)
+			}
+
+			c.template = tpl
+		}
+
+		if colType.Encoding.Enabled {
+			decoder, err := Decoder(colType.Encoding)
+			if err != nil {
+				return nil, fmt.Errorf("encoding of column %s: %w", name, err)
+			}
+
+			c.decoder = decoder
+			plan.decoded[idx] = true
+		}
+
+		for _, zone := range []string{colType.Date.SourceZone, colType.Date.DestinationZone} {
+			if _, err := loadLocation(zone); err != nil {
+				return nil, fmt.Errorf("date of column %s: %w", name, err)
+			}
+		}
+
+		if err := c.compileNull(); err != nil {
+			return nil, fmt.Errorf("null policy of column %s: %w", name, err)
+		}
+
+		mask, err := newMasker(colType.Mask, colType.Type, salt)
+		if err != nil {
+			return nil, fmt.Errorf("mask of column %s: %w", name, err)
+		}
+
+		c.mask = mask
+
+		plan.columns = append(plan.columns, c)
+	}
+
+	return plan, nil
+}
+
+// decodes reports whether the column at index is decoded from an encoding, its value must be kept as read.
+func (p *mappingPlan) decodes(index int) bool {
+	return p != nil && p.decoded[index]
+}
+
+// apply converts values of the row in place.
+func (p *mappingPlan) apply(result []any) error {
+	if p == nil {
+		return nil
+	}
+
+	for _, c := range p.columns {
+		v := indirect(result[c.index])
+		if c.Null.EmptyToNull && isEmpty(v) {
+			v = nil
+		}
+
+		if c.Null.OnNull != "" && isNull(v) {
+			if c.Null.OnNull == service.NullFail {
+				return fmt.Errorf("column %s: %w", c.name, ErrNullValue)
+			}
+
+			result[c.index] = c.nullValue
+
+			continue
+		}
+
+		if c.mask != nil {
+			if c.mask.Type == service.MaskNull {
+				result[c.index] = nil
+
+				continue
+			}
+
+			v = c.mask.mask(v)
+		}
+
+		v, err := c.convert(c, v)
+		if err != nil {
+			return fmt.Errorf("column %s: %w", c.name, err)
+		}
+
+		result[c.index] = v
+	}
+
+	return nil
+}
+
+// compileNull prepares the replacement value of the null policy.
+func (c *columnMapping) compileNull() error {
+	switch c.Null.OnNull {
+	case "", service.NullFail:
+	case service.NullDefault:
+		v, err := c.convert(c, c.Null.Default)
+		if err != nil {
+			return fmt.Errorf("convert default %q: %w", c.Null.Default, err)
+		}
+
+		c.nullValue = v
+	case service.NullEmpty:
+		switch c.Type {
+		case "string":
+			if c.Nullable {
+				c.nullValue = types.NewNull("")
+			} else {
+				c.nullValue = ""
+			}
+		case "bytes":
+			c.nullValue = []byte{}
+		default:
+			return fmt.Errorf("empty is not supported for type %s; %w", c.Type, service.ErrBadRequest)
+		}
+	default:
+		return fmt.Errorf("unknown on_null %q; %w", c.Null.OnNull, service.ErrBadRequest)
+	}
+
+	return nil
+}
+
+func isNull(v any) bool {
+	if v == nil {
+		return true
+	}
+
+	if valuer, ok := v.(driver.Valuer); ok {
+		value, err := valuer.Value()
+
+		return err == nil && value == nil
+	}
+
+	return false
+}
+
+func isEmpty(v any) bool {
+	switch val := v.(type) {
+	case string:
+		return strings.TrimSpace(val) == ""
+	case []byte:
+		return len(bytes.TrimSpace(val)) == 0
+	case types.Null[string]:
+		return val.Valid && strings.TrimSpace(val.V) == ""
+	}
+
+	return false
+}
+
+var converters = map[string]func(c *columnMapping, v any) (any, error){
+	"string": func(c *columnMapping, v any) (any, error) {
+		vStr, err := getAnyString(v, c)
+		if err != nil {
+			return nil, err
+		}
+
+		if c.Nullable {
+			return vStr, nil
+		}
+
+		return vStr.V, nil
+	},
+	"number": func(c *columnMapping, v any) (any, error) {
+		vNum, err := getAnyNumber(v, c)
+		if err != nil {
+			return nil, err
+		}
+
+		if c.Nullable {
+			return vNum, nil
+		}
+
+		return vNum.Decimal, nil
+	},
+	"date": func(c *columnMapping, v any) (any, error) {
+		vDate, err := getAnyDate(v, c)
+		if err != nil {
+			return nil, err
+		}
+
+		if c.mask != nil && vDate.Valid {
+			vDate.V.Time = c.mask.shift(vDate.V.Time)
+		}
+
+		switch {
+		case c.Date.OutputLayout != "":
+			vDateStr := types.NewNullWithValid(formatDate(vDate.V.Time, c.Date), vDate.Valid)
+			if c.Nullable {
+				return vDateStr, nil
+			}
+
+			return vDateStr.V, nil
+		case c.Nullable:
+			return vDate, nil
+		}
+
+		return vDate.V, nil
+	},
+	"integer": func(c *columnMapping, v any) (any, error) {
+		vInt, err := getAnyInteger(v, c)
+		if err != nil {
+			return nil, err
+		}
+
+		if c.Nullable {
+			return vInt, nil
+		}
+
+		return vInt.V, nil
+	},
+	"boolean": func(c *columnMapping, v any) (any, error) {
+		vBool, err := getAnyBool(v, c)
+		if err != nil {
+			return nil, err
+		}
+
+		if c.Nullable {
+			return vBool, nil
+		}
+
+		return vBool.V, nil
+	},
+	"json": func(c *columnMapping, v any) (any, error) {
+		return getAnyJSON(v, c)
+	},
+	"uuid": func(c *columnMapping, v any) (any, error) {
+		vUUID, err := getAnyUUID(v, c)
+		if err != nil {
+			return nil, err
+		}
+
+		return uuidValue(vUUID, c), nil
+	},
+	"bytes": func(c *columnMapping, v any) (any, error) {
+		return getAnyBytes(v, c)
+	},
+}
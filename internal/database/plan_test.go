@@ -0,0 +1,286 @@
+package database
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/spf13/cast"
+	"github.com/stretchr/testify/require"
+	"github.com/worldline-go/saz/internal/render"
+	"github.com/worldline-go/saz/internal/service"
+	"github.com/worldline-go/types"
+)
+
+var benchMapType = service.MapType{
+	Enabled: true,
+	Destination: map[string]service.ColumnTypeTemplate{
+		"name": {
+			Type:     "string",
+			Template: service.EnableValue{Enabled: true, Value: `{{ . | upper }}`},
+		},
+		"amount": {
+			Type:     "number",
+			Template: service.EnableValue{Enabled: true, Value: `{{ . }}0`},
+		},
+		"created": {
+			Type:     "date",
+			Template: service.EnableValue{Enabled: true, Value: `{{ . }}`},
+			Date:     service.Date{DestinationZone: "UTC"},
+		},
+		"raw": {
+			Type:     "string",
+			Encoding: service.Encoding{Enabled: true, Coding: "windows-1252"},
+		},
+	},
+}
+
+var benchColumns = []string{"id", "name", "amount", "created", "raw"}
+
+func benchRows(n int) [][]any {
+	rows := make([][]any, n)
+	created := time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC)
+
+	for i := range rows {
+		rows[i] = []any{
+			int64(i),
+			types.NewNull("name-" + strconv.Itoa(i)),
+			decimal.NewFromInt(int64(i)),
+			created.Add(time.Duration(i) * time.Second).Format(time.DateTime),
+			[]byte("caf\xe9"),
+		}
+	}
+
+	return rows
+}
+
+func TestMappingPlan(t *testing.T) {
+	plan, err := compileMapping("", benchColumns, benchMapType, "")
+	require.NoError(t, err)
+
+	row := benchRows(2)[1]
+	require.NoError(t, plan.apply(row))
+
+	require.Equal(t, int64(1), row[0])
+	require.Equal(t, "NAME-1", row[1])
+	require.Equal(t, "10", row[2].(decimal.Decimal).String())
+	require.Equal(t, time.Date(2024, 3, 1, 10, 30, 1, 0, time.UTC), row[3].(types.Time).Time)
+	require.Equal(t, "café", row[4])
+
+	_, err = compileMapping("", benchColumns, service.MapType{
+		Enabled: true,
+		Destination: map[string]service.ColumnTypeTemplate{
+			"name": {Type: "string", Template: service.EnableValue{Enabled: true, Value: `{{ .`}},
+		},
+	}, "")
+	require.Error(t, err)
+
+	plan, err = compileMapping("", benchColumns, service.MapType{}, "")
+	require.NoError(t, err)
+	require.Nil(t, plan)
+	require.NoError(t, plan.apply(row))
+
+	content := `{{ define "wrap" }}[{{ . }}]{{ end }}{{ execTemplate "wrap" . }}`
+	plan, err = compileMapping("", benchColumns, service.MapType{
+		Enabled: true,
+		Destination: map[string]service.ColumnTypeTemplate{
+			"name": {Type: "string", Template: service.EnableValue{Enabled: true, Value: content}},
+		},
+	}, "")
+	require.NoError(t, err)
+
+	row = benchRows(1)[0]
+	require.NoError(t, plan.apply(row))
+
+	rendered, err := render.ExecuteWithData(content, "name-0")
+	require.NoError(t, err)
+	require.Equal(t, string(rendered), row[1])
+	require.Equal(t, "[name-0]", row[1])
+}
+
+// BenchmarkMapPerRow is the previous behaviour, every value is decoded with Decode and rendered with
+// render.ExecuteWithData, which parses the template again, before its type conversion.
+func BenchmarkMapPerRow(b *testing.B) {
+	rows := benchRows(10_000)
+	row := make([]any, len(benchColumns))
+
+	convertOnly := service.MapType{Enabled: true, Destination: map[string]service.ColumnTypeTemplate{}}
+	for name, colType := range benchMapType.Destination {
+		colType.Template = service.EnableValue{}
+		colType.Encoding = service.Encoding{}
+		convertOnly.Destination[name] = colType
+	}
+
+	plan, err := compileMapping("", benchColumns, convertOnly, "")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+
+	for b.Loop() {
+		for _, src := range rows {
+			copy(row, src)
+
+			for i, name := range benchColumns {
+				colType, ok := benchMapType.Destination[name]
+				if !ok {
+					continue
+				}
+
+				v := row[i]
+				if colType.Encoding.Enabled {
+					decoded, err := Decode(v.([]byte), colType.Encoding)
+					if err != nil {
+						b.Fatal(err)
+					}
+
+					v = decoded
+				}
+
+				if colType.Template.Enabled {
+					if n, ok := v.(types.Null[string]); ok {
+						v = n.V
+					}
+
+					rendered, err := render.ExecuteWithData(colType.Template.Value, cast.ToString(v))
+					if err != nil {
+						b.Fatal(err)
+					}
+
+					v = string(rendered)
+				}
+
+				row[i] = v
+			}
+
+			if err := plan.apply(row); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkMapPlan(b *testing.B) {
+	rows := benchRows(10_000)
+	row := make([]any, len(benchColumns))
+
+	b.ReportAllocs()
+
+	for b.Loop() {
+		plan, err := compileMapping("", benchColumns, benchMapType, "")
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		for _, src := range rows {
+			copy(row, src)
+
+			if err := plan.apply(row); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestNullPolicy(t *testing.T) {
+	tests := []struct {
+		name       string
+		value      any
+		column     service.ColumnTypeTemplate
+		want       any
+		wantErr    bool
+		compileErr bool
+	}{
+		{
+			name:   "null kept",
+			value:  types.NewNullWithValid("", false),
+			column: service.ColumnTypeTemplate{Type: "string", Nullable: true},
+			want:   types.NewNullWithValid("", false),
+		},
+		{
+			name:   "default number",
+			value:  nil,
+			column: service.ColumnTypeTemplate{Type: "number", Null: service.NullPolicy{OnNull: service.NullDefault, Default: "0"}},
+			want:   decimal.RequireFromString("0"),
+		},
+		{
+			name:   "default integer of null decimal",
+			value:  types.NullDecimal{},
+			column: service.ColumnTypeTemplate{Type: "integer", Nullable: true, Null: service.NullPolicy{OnNull: service.NullDefault, Default: "-1"}},
+			want:   types.NewNull[int64](-1),
+		},
+		{
+			name:   "empty to null",
+			value:  types.NewNull("   "),
+			column: service.ColumnTypeTemplate{Type: "string", Nullable: true, Null: service.NullPolicy{EmptyToNull: true}},
+			want:   types.NewNullWithValid("", false),
+		},
+		{
+			name:   "empty to default date",
+			value:  []byte(""),
+			column: service.ColumnTypeTemplate{Type: "date", Null: service.NullPolicy{EmptyToNull: true, OnNull: service.NullDefault, Default: "1970-01-01"}},
+			want:   types.NewTime(time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)),
+		},
+		{
+			name:   "null to empty",
+			value:  types.NewNullWithValid("", false),
+			column: service.ColumnTypeTemplate{Type: "string", Null: service.NullPolicy{OnNull: service.NullEmpty}},
+			want:   "",
+		},
+		{
+			name:   "value not replaced",
+			value:  "x",
+			column: service.ColumnTypeTemplate{Type: "string", Null: service.NullPolicy{EmptyToNull: true, OnNull: service.NullFail}},
+			want:   "x",
+		},
+		{
+			name:    "fail on null",
+			value:   UUID{},
+			column:  service.ColumnTypeTemplate{Type: "uuid", Null: service.NullPolicy{OnNull: service.NullFail}},
+			wantErr: true,
+		},
+		{
+			name:       "empty for number",
+			column:     service.ColumnTypeTemplate{Type: "number", Null: service.NullPolicy{OnNull: service.NullEmpty}},
+			compileErr: true,
+		},
+		{
+			name:       "invalid default",
+			column:     service.ColumnTypeTemplate{Type: "integer", Null: service.NullPolicy{OnNull: service.NullDefault, Default: "abc"}},
+			compileErr: true,
+		},
+		{
+			name:       "unknown policy",
+			column:     service.ColumnTypeTemplate{Type: "string", Null: service.NullPolicy{OnNull: "zero"}},
+			compileErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := compileMapping("", []string{"col"}, service.MapType{
+				Enabled:     true,
+				Destination: map[string]service.ColumnTypeTemplate{"col": tt.column},
+			}, "")
+			if tt.compileErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+
+			row := []any{tt.value}
+
+			err = plan.apply(row)
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrNullValue)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.want, row[0])
+		})
+	}
+}
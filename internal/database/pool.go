@@ -0,0 +1,108 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/worldline-go/saz/internal/service"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// PoolStats returns the connection pool statistics of the databases.
+func (d *Database) PoolStats() map[string]service.PoolStats {
+	infos := d.infos()
+
+	stats := make(map[string]service.PoolStats, len(infos))
+	for name, dbConn := range infos {
+		db := dbConn.conn()
+		if db == nil {
+			continue
+		}
+
+		s := db.Stats()
+
+		stats[name] = service.PoolStats{
+			MaxOpenConnections: s.MaxOpenConnections,
+			OpenConnections:    s.OpenConnections,
+			InUse:              s.InUse,
+			Idle:               s.Idle,
+			WaitCount:          s.WaitCount,
+			WaitDurationMS:     float64(s.WaitDuration.Microseconds()) / 1000,
+			MaxIdleClosed:      s.MaxIdleClosed,
+			MaxIdleTimeClosed:  s.MaxIdleTimeClosed,
+			MaxLifetimeClosed:  s.MaxLifetimeClosed,
+		}
+	}
+
+	return stats
+}
+
+// RegisterPoolMetrics reports pool statistics of the databases to the global meter provider of telemetry.
+func (d *Database) RegisterPoolMetrics() error {
+	meter := otel.GetMeterProvider().Meter("saz")
+
+	var errs []error
+	gauge := func(name, description string) metric.Int64ObservableGauge {
+		g, err := meter.Int64ObservableGauge(name, metric.WithDescription(description))
+		errs = append(errs, err)
+
+		return g
+	}
+
+	counter := func(name, description string) metric.Int64ObservableCounter {
+		c, err := meter.Int64ObservableCounter(name, metric.WithDescription(description))
+		errs = append(errs, err)
+
+		return c
+	}
+
+	maxOpen := gauge("saz_db_pool_max_open_connections", "maximum number of open connections to the database")
+	open := gauge("saz_db_pool_open_connections", "number of established connections both in use and idle")
+	inUse := gauge("saz_db_pool_in_use_connections", "number of connections currently in use")
+	idle := gauge("saz_db_pool_idle_connections", "number of idle connections")
+	waitCount := counter("saz_db_pool_wait_count", "total number of connections waited for")
+	maxIdleClosed := counter("saz_db_pool_max_idle_closed", "total number of connections closed due to max idle connections")
+	maxIdleTimeClosed := counter("saz_db_pool_max_idle_time_closed", "total number of connections closed due to max idle time")
+	maxLifetimeClosed := counter("saz_db_pool_max_lifetime_closed", "total number of connections closed due to max connection lifetime")
+
+	waitDuration, err := meter.Float64ObservableCounter("saz_db_pool_wait_duration",
+		metric.WithDescription("total time blocked waiting for a new connection"), metric.WithUnit("s"))
+	errs = append(errs, err)
+
+	for _, err := range errs {
+		if err != nil {
+			return fmt.Errorf("create pool metric: %w", err)
+		}
+	}
+
+	_, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		for name, dbConn := range d.infos() {
+			db := dbConn.conn()
+			if db == nil {
+				continue
+			}
+
+			s := db.Stats()
+			attrs := metric.WithAttributes(attribute.String("database", name))
+
+			o.ObserveInt64(maxOpen, int64(s.MaxOpenConnections), attrs)
+			o.ObserveInt64(open, int64(s.OpenConnections), attrs)
+			o.ObserveInt64(inUse, int64(s.InUse), attrs)
+			o.ObserveInt64(idle, int64(s.Idle), attrs)
+			o.ObserveInt64(waitCount, s.WaitCount, attrs)
+			o.ObserveFloat64(waitDuration, s.WaitDuration.Seconds(), attrs)
+			o.ObserveInt64(maxIdleClosed, s.MaxIdleClosed, attrs)
+			o.ObserveInt64(maxIdleTimeClosed, s.MaxIdleTimeClosed, attrs)
+			o.ObserveInt64(maxLifetimeClosed, s.MaxLifetimeClosed, attrs)
+		}
+
+		return nil
+	}, maxOpen, open, inUse, idle, waitCount, waitDuration, maxIdleClosed, maxIdleTimeClosed, maxLifetimeClosed)
+	if err != nil {
+		return fmt.Errorf("register pool metrics: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package database
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/worldline-go/saz/internal/config"
+	"go.opentelemetry.io/otel"
+	metricsdk "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestPoolStats(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	db.SetMaxOpenConns(2)
+
+	conn, err := db.Conn(t.Context())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	d := &Database{DB: map[string]*Info{"sqlite": {DB: db, DBType: "sqlite3"}}}
+
+	stats := d.PoolStats()
+	require.Equal(t, 2, stats["sqlite"].MaxOpenConnections)
+	require.Equal(t, 1, stats["sqlite"].InUse)
+
+	reader := metricsdk.NewManualReader()
+	otel.SetMeterProvider(metricsdk.NewMeterProvider(metricsdk.WithReader(reader)))
+
+	require.NoError(t, d.RegisterPoolMetrics())
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(t.Context(), &rm))
+
+	inUse := map[string]int64{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if gauge, ok := m.Data.(metricdata.Gauge[int64]); ok && m.Name == "saz_db_pool_in_use_connections" {
+				for _, dp := range gauge.DataPoints {
+					name, _ := dp.Attributes.Value("database")
+					inUse[name.AsString()] = dp.Value
+				}
+			}
+		}
+	}
+
+	require.Equal(t, map[string]int64{"sqlite": 1}, inUse)
+}
+
+func TestSetPool(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	setPool(db, config.Database{})
+	require.Equal(t, 3, db.Stats().MaxOpenConnections)
+
+	setPool(db, config.Database{MaxOpenConns: 10, ConnMaxLifetime: time.Hour})
+	require.Equal(t, 10, db.Stats().MaxOpenConnections)
+}
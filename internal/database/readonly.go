@@ -0,0 +1,213 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/worldline-go/saz/internal/service"
+)
+
+// readKeywords are the first keywords of statements allowed on read only databases.
+var readKeywords = map[string]struct{}{
+	"SELECT":   {},
+	"WITH":     {},
+	"VALUES":   {},
+	"TABLE":    {},
+	"SHOW":     {},
+	"EXPLAIN":  {},
+	"DESCRIBE": {},
+	"DESC":     {},
+	"PRAGMA":   {},
+}
+
+// writeKeywords are not allowed anywhere in a statement on read only databases,
+// like writable CTEs, SELECT INTO and FOR UPDATE.
+// T-SQL statements don't need a terminator, so its server commands are also listed.
+var writeKeywords = map[string]struct{}{
+	"INSERT":   {},
+	"UPDATE":   {},
+	"DELETE":   {},
+	"MERGE":    {},
+	"UPSERT":   {},
+	"INTO":     {},
+	"CREATE":   {},
+	"ALTER":    {},
+	"DROP":     {},
+	"TRUNCATE": {},
+	"RENAME":   {},
+	"GRANT":    {},
+	"REVOKE":   {},
+	"COMMIT":   {},
+	"ROLLBACK": {},
+	"CALL":     {},
+	"EXEC":     {},
+	"EXECUTE":  {},
+	"COPY":     {},
+
+	"SHUTDOWN":    {},
+	"DENY":        {},
+	"DISABLE":     {},
+	"ENABLE":      {},
+	"KILL":        {},
+	"BACKUP":      {},
+	"RESTORE":     {},
+	"DBCC":        {},
+	"RECONFIGURE": {},
+	"DECLARE":     {},
+	"SET":         {},
+	"USE":         {},
+	"BEGIN":       {},
+	"GO":          {},
+}
+
+type execQueryer interface {
+	queryer
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// txBeginner is a connection pool or a single connection.
+type txBeginner interface {
+	execQueryer
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// ReadStatement reports whether the statement only reads, unknown statements are writes.
+// Functions with side effects are not detected, read only transactions of the database cover them.
+func ReadStatement(dbType, stmt string) bool {
+	words := statementWords(dbType, stmt)
+	if len(words) == 0 {
+		return false
+	}
+
+	if _, ok := readKeywords[words[0]]; !ok {
+		return false
+	}
+
+	// pragma assignments change the database
+	if words[0] == "PRAGMA" && strings.Contains(stmt, "=") {
+		return false
+	}
+
+	for _, word := range words[1:] {
+		if _, ok := writeKeywords[word]; ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// statementWords returns the upper case words of stmt, strings, quoted identifiers and comments are skipped.
+func statementWords(dbType, stmt string) []string {
+	var words []string
+
+	for i := 0; i < len(stmt); {
+		c := stmt[i]
+
+		switch {
+		case c == '\'' || c == '"' || c == '`' || c == '[' && dbType == "sqlserver":
+			if c == '[' {
+				end := strings.IndexByte(stmt[i:], ']')
+				if end < 0 {
+					return words
+				}
+
+				i += end + 1
+
+				continue
+			}
+
+			i = skipQuoted(stmt, i, c, dbType == "mysql" && c != '`')
+		case c == '-' && strings.HasPrefix(stmt[i:], "--"), c == '#' && dbType == "mysql":
+			_, i = nextLine(stmt, i)
+		case c == '/' && strings.HasPrefix(stmt[i:], "/*"):
+			end := strings.Index(stmt[i+2:], "*/")
+			if end < 0 {
+				return words
+			}
+
+			i += end + 4
+		case c == '$' && (dbType == "pgx" || dbType == "postgres"):
+			i = skipDollarQuoted(stmt, i)
+		case isIdentStart(c):
+			end := i + 1
+			for end < len(stmt) && isIdentPart(stmt[end]) {
+				end++
+			}
+
+			// :name parameters and @variables are not keywords
+			if i == 0 || (stmt[i-1] != ':' && stmt[i-1] != '@' && stmt[i-1] != '.') {
+				words = append(words, strings.ToUpper(stmt[i:end]))
+			}
+
+			i = end
+		default:
+			i++
+		}
+	}
+
+	return words
+}
+
+// checkReadOnly returns ErrForbidden when the database is read only and the query has a statement that can write.
+func checkReadOnly(name string, dbConn *Info, query string) error {
+	if !dbConn.ReadOnly {
+		return nil
+	}
+
+	for i, stmt := range readStatements(dbConn.DBType, query) {
+		if !ReadStatement(dbConn.DBType, stmt) {
+			return fmt.Errorf("database %s is read only, statement %d is not allowed; %w", name, i+1, service.ErrForbidden)
+		}
+	}
+
+	return nil
+}
+
+// readStatements splits the query to the statements to check, batches of SQL Server are also split with ;.
+func readStatements(dbType, query string) []string {
+	var statements []string
+	for _, stmt := range SplitStatements(dbType, query) {
+		if dbType == "sqlserver" {
+			statements = append(statements, SplitStatements("", stmt)...)
+
+			continue
+		}
+
+		statements = append(statements, stmt)
+	}
+
+	return statements
+}
+
+// readOnlyTx reports whether reads of the database run in read only transactions.
+// Drivers of SQL Server and SQLite don't support them, sqlite can be opened with mode=ro instead.
+func readOnlyTx(dbConn *Info) bool {
+	if !dbConn.ReadOnly {
+		return false
+	}
+
+	switch dbConn.DBType {
+	case "pgx", "postgres", "mysql", "godror":
+		return true
+	}
+
+	return false
+}
+
+// session returns where the statements run with the release function to call after,
+// a read only transaction for read only databases or db itself.
+func session(ctx context.Context, dbConn *Info, db txBeginner) (execQueryer, func(), error) {
+	if !readOnlyTx(dbConn) {
+		return db, func() {}, nil
+	}
+
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, nil, fmt.Errorf("begin read only transaction: %w", err)
+	}
+
+	return tx, func() { _ = tx.Rollback() }, nil
+}
@@ -0,0 +1,111 @@
+package database
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/worldline-go/saz/internal/service"
+)
+
+func TestReadStatement(t *testing.T) {
+	tests := []struct {
+		name   string
+		dbType string
+		stmt   string
+		want   bool
+	}{
+		{name: "select", dbType: "pgx", stmt: "SELECT * FROM users WHERE id = :id", want: true},
+		{name: "comment first", dbType: "pgx", stmt: "-- users\n/* all */ select 1", want: true},
+		{name: "cte", dbType: "pgx", stmt: "WITH a AS (SELECT 1) SELECT * FROM a", want: true},
+		{name: "keyword in string", dbType: "pgx", stmt: "SELECT 'DELETE FROM users' AS q", want: true},
+		{name: "keyword as quoted identifier", dbType: "pgx", stmt: `SELECT "update" FROM logs`, want: true},
+		{name: "sqlserver bracket identifier", dbType: "sqlserver", stmt: "SELECT [Insert] FROM logs", want: true},
+		{name: "mysql hash comment", dbType: "mysql", stmt: "SHOW TABLES # DROP", want: true},
+		{name: "pragma read", dbType: "sqlite3", stmt: "PRAGMA table_info(users)", want: true},
+		{name: "insert", dbType: "pgx", stmt: "INSERT INTO users VALUES (1)"},
+		{name: "writable cte", dbType: "pgx", stmt: "WITH d AS (DELETE FROM users RETURNING *) SELECT * FROM d"},
+		{name: "select into", dbType: "sqlserver", stmt: "SELECT * INTO backup FROM users"},
+		{name: "for update", dbType: "godror", stmt: "SELECT * FROM users FOR UPDATE"},
+		{name: "explain analyze write", dbType: "pgx", stmt: "EXPLAIN ANALYZE DELETE FROM users"},
+		{name: "set", dbType: "pgx", stmt: "SET TRANSACTION READ WRITE"},
+		{name: "procedure", dbType: "sqlserver", stmt: "EXEC cleanup"},
+		{name: "pragma write", dbType: "sqlite3", stmt: "PRAGMA journal_mode = WAL"},
+		{name: "empty", dbType: "pgx", stmt: "-- nothing"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, ReadStatement(tt.dbType, tt.stmt))
+		})
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec("CREATE TABLE users (id INTEGER, name TEXT); INSERT INTO users VALUES (1, 'a')")
+	require.NoError(t, err)
+
+	d := &Database{DB: map[string]*Info{"replica": {DB: db, DBType: "sqlite3", ReadOnly: true}}}
+
+	result, err := d.Query(t.Context(), "replica", "SELECT name FROM users", nil, 0, false, service.Snapshot{})
+	require.NoError(t, err)
+	require.Equal(t, [][]any{{"a"}}, result.Rows())
+
+	_, err = d.Exec(t.Context(), "replica", "DELETE FROM users", nil)
+	require.ErrorIs(t, err, service.ErrForbidden)
+
+	_, err = d.Query(t.Context(), "replica", "SELECT 1; DROP TABLE users", nil, 0, false, service.Snapshot{})
+	require.ErrorIs(t, err, service.ErrForbidden)
+
+	_, err = d.Script(t.Context(), "replica", "SELECT 1;\nUPDATE users SET name = 'b';", nil, 0, false)
+	require.ErrorIs(t, err, service.ErrForbidden)
+	require.ErrorContains(t, err, "statement 2")
+
+	_, err = d.IterSet(t.Context(), "replica", "users", false, service.SkipError{}, service.MapType{}, service.LOB{}, 0, []string{"id"}, nil)
+	require.ErrorIs(t, err, service.ErrForbidden)
+
+	result, err = d.Query(t.Context(), "replica", "SELECT count(*) FROM users", nil, 0, false, service.Snapshot{})
+	require.NoError(t, err)
+	require.Equal(t, [][]any{{"1"}}, result.Rows())
+}
+
+func TestCheckReadOnlySQLServer(t *testing.T) {
+	dbConn := &Info{DBType: "sqlserver", ReadOnly: true}
+
+	tests := []struct {
+		name  string
+		query string
+		want  bool
+	}{
+		{name: "select", query: "SELECT [id] FROM users WHERE name = 'a;b'", want: true},
+		{name: "batches", query: "SELECT 1;\nSELECT 2\nGO\nWITH a AS (SELECT 1 AS n) SELECT n FROM a", want: true},
+		{name: "shutdown", query: "SELECT 1; SHUTDOWN WITH NOWAIT"},
+		{name: "deny", query: "SELECT 1; DENY SELECT ON users TO public"},
+		{name: "disable trigger", query: "SELECT 1; DISABLE TRIGGER trg ON users"},
+		{name: "kill", query: "SELECT 1; KILL 53"},
+		{name: "backup", query: "SELECT 1; BACKUP DATABASE x TO DISK='/tmp/x.bak'"},
+		{name: "dbcc", query: "SELECT 1; DBCC SHRINKDATABASE(x)"},
+		{name: "without terminator", query: "SELECT 1 SHUTDOWN WITH NOWAIT"},
+		{name: "second batch", query: "SELECT 1\nGO\nKILL 53"},
+		{name: "select into", query: "SELECT 1; SELECT * INTO copy FROM users"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkReadOnly("replica", dbConn, tt.query)
+			if tt.want {
+				require.NoError(t, err)
+
+				return
+			}
+
+			require.ErrorIs(t, err, service.ErrForbidden)
+		})
+	}
+}
@@ -2,13 +2,18 @@ package database
 
 import (
 	"time"
+
+	"github.com/worldline-go/saz/internal/service"
 )
 
 type Result struct {
 	columns      []string
+	columnTypes  []service.ColumnInfo
 	duration     time.Duration
 	rowsAffected int64 // This can be set if using sql.Result
 	rows         [][]any
+	results      []service.Result
+	plan         *service.PlanNode
 }
 
 func (r *Result) RowsAffected() int64 {
@@ -26,3 +31,17 @@ func (r *Result) Rows() [][]any {
 func (r *Result) Columns() []string {
 	return r.columns
 }
+
+// Results returns result sets of a script.
+func (r *Result) Results() []service.Result {
+	return r.results
+}
+
+// Plan returns the plan of an explained query.
+func (r *Result) Plan() *service.PlanNode {
+	return r.plan
+}
+
+func (r *Result) ColumnTypes() []service.ColumnInfo {
+	return r.columnTypes
+}
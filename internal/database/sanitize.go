@@ -2,18 +2,126 @@ package database
 
 import (
 	"bytes"
-	"io"
+	"fmt"
 	"strings"
 	"unicode/utf8"
 
+	"github.com/worldline-go/saz/internal/service"
+	"golang.org/x/text/encoding"
 	"golang.org/x/text/encoding/charmap"
-	"golang.org/x/text/transform"
+	"golang.org/x/text/encoding/ianaindex"
+	"golang.org/x/text/encoding/unicode"
 )
 
 const (
 	EncodingISO88591 = "ISO 8859-1"
+	// EncodingAuto detects UTF-8 and UTF-16 by byte order mark and validity,
+	// other data is decoded with the fallback encoding.
+	EncodingAuto = "auto"
+
+	defaultFallbackEncoding = "windows-1252"
 )
 
+// encodings holds encodings with normalized names, like "iso88591" and "ibmcodepage037".
+var encodings = map[string]encoding.Encoding{
+	"utf8":    unicode.UTF8,
+	"utf16":   unicode.UTF16(unicode.BigEndian, unicode.UseBOM),
+	"utf16be": unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM),
+	"utf16le": unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM),
+}
+
+func init() {
+	for _, enc := range charmap.All {
+		if name, ok := enc.(fmt.Stringer); ok {
+			encodings[normalizeEncodingName(name.String())] = enc
+		}
+	}
+}
+
+func normalizeEncodingName(name string) string {
+	return strings.NewReplacer(" ", "", "-", "", "_", "").Replace(strings.ToLower(strings.TrimSpace(name)))
+}
+
+// LookupEncoding finds an encoding by its display name (ISO 8859-9) or IANA name and aliases (windows-1252, IBM037, latin1).
+func LookupEncoding(name string) (encoding.Encoding, error) {
+	if enc, ok := encodings[normalizeEncodingName(name)]; ok {
+		return enc, nil
+	}
+
+	enc, err := ianaindex.IANA.Encoding(name)
+	if err != nil || enc == nil {
+		return nil, fmt.Errorf("unsupported encoding %q; %w", name, service.ErrBadRequest)
+	}
+
+	return enc, nil
+}
+
+// Decode converts data in the given encoding to UTF-8.
+func Decode(data []byte, enc service.Encoding) (string, error) {
+	decode, err := Decoder(enc)
+	if err != nil {
+		return "", err
+	}
+
+	return decode(data)
+}
+
+// Decoder resolves the encoding once and returns a function converting data to UTF-8.
+func Decoder(enc service.Encoding) (func(data []byte) (string, error), error) {
+	if !enc.Enabled || enc.Coding == "" {
+		return func(data []byte) (string, error) {
+			return string(data), nil
+		}, nil
+	}
+
+	if enc.Coding == EncodingAuto {
+		fallback := enc.Fallback
+		if fallback == "" {
+			fallback = defaultFallbackEncoding
+		}
+
+		decoding, err := LookupEncoding(fallback)
+		if err != nil {
+			return nil, err
+		}
+
+		return func(data []byte) (string, error) {
+			return decodeAuto(data, decoding)
+		}, nil
+	}
+
+	decoding, err := LookupEncoding(enc.Coding)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(data []byte) (string, error) {
+		return decodeWith(data, decoding)
+	}, nil
+}
+
+func decodeAuto(data []byte, fallback encoding.Encoding) (string, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xef, 0xbb, 0xbf}):
+		return string(data[3:]), nil
+	case bytes.HasPrefix(data, []byte{0xfe, 0xff}), bytes.HasPrefix(data, []byte{0xff, 0xfe}):
+		return decodeWith(data, unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM))
+	case utf8.Valid(data):
+		return string(data), nil
+	}
+
+	return decodeWith(data, fallback)
+}
+
+func decodeWith(data []byte, enc encoding.Encoding) (string, error) {
+	utf8Bytes, err := enc.NewDecoder().Bytes(data)
+	if err != nil {
+		return "", fmt.Errorf("decode %v: %w", enc, err)
+	}
+
+	return string(utf8Bytes), nil
+}
+
 func SanitizeString(s string) string {
 	if utf8.ValidString(s) {
 		return s
@@ -35,15 +143,3 @@ func SanitizeString(s string) string {
 
 	return b.String()
 }
-
-func ConvertISO88591ToUTF8(data []byte) (string, error) {
-	decoder := charmap.ISO8859_1.NewDecoder()
-	reader := transform.NewReader(bytes.NewReader(data), decoder)
-
-	utf8Bytes, err := io.ReadAll(reader)
-	if err != nil {
-		return "", err
-	}
-
-	return string(utf8Bytes), nil
-}
@@ -1,6 +1,12 @@
 package database
 
-import "testing"
+import (
+	"database/sql"
+	"iter"
+	"testing"
+
+	"github.com/worldline-go/saz/internal/service"
+)
 
 func TestSanitizeString(t *testing.T) {
 	tests := []struct {
@@ -28,3 +34,211 @@ func TestSanitizeString(t *testing.T) {
 		})
 	}
 }
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name    string
+		arg     []byte
+		enc     service.Encoding
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "disabled",
+			arg:  []byte("abc"),
+			enc:  service.Encoding{Enabled: false, Coding: "IBM037"},
+			want: "abc",
+		},
+		{
+			name: "iso 8859-1",
+			arg:  []byte{0x41, 0xe9},
+			enc:  service.Encoding{Enabled: true, Coding: EncodingISO88591},
+			want: "Aé",
+		},
+		{
+			name: "windows-1252",
+			arg:  []byte{0x80, 0x20, 0x31},
+			enc:  service.Encoding{Enabled: true, Coding: "windows-1252"},
+			want: "€ 1",
+		},
+		{
+			name: "iso 8859-9 turkish",
+			arg:  []byte{0xdd, 0xfe, 0xf0},
+			enc:  service.Encoding{Enabled: true, Coding: "ISO-8859-9"},
+			want: "İşğ",
+		},
+		{
+			name: "iso 8859-15",
+			arg:  []byte{0xa4},
+			enc:  service.Encoding{Enabled: true, Coding: "ISO 8859-15"},
+			want: "€",
+		},
+		{
+			name: "ebcdic ibm037",
+			arg:  []byte{0xc8, 0x85, 0x93, 0x93, 0x96},
+			enc:  service.Encoding{Enabled: true, Coding: "IBM037"},
+			want: "Hello",
+		},
+		{
+			name: "ebcdic ibm1047",
+			arg:  []byte{0xad, 0xbd},
+			enc:  service.Encoding{Enabled: true, Coding: "ibm1047"},
+			want: "[]",
+		},
+		{
+			name: "utf-16 with bom",
+			arg:  []byte{0xff, 0xfe, 0x41, 0x00, 0x42, 0x00},
+			enc:  service.Encoding{Enabled: true, Coding: "UTF-16"},
+			want: "AB",
+		},
+		{
+			name: "utf-16le",
+			arg:  []byte{0x41, 0x00, 0x42, 0x00},
+			enc:  service.Encoding{Enabled: true, Coding: "UTF-16LE"},
+			want: "AB",
+		},
+		{
+			name: "auto utf-8",
+			arg:  []byte("Aé"),
+			enc:  service.Encoding{Enabled: true, Coding: EncodingAuto},
+			want: "Aé",
+		},
+		{
+			name: "auto utf-16 bom",
+			arg:  []byte{0xfe, 0xff, 0x00, 0x41},
+			enc:  service.Encoding{Enabled: true, Coding: EncodingAuto},
+			want: "A",
+		},
+		{
+			name: "auto fallback",
+			arg:  []byte{0x41, 0xfd},
+			enc:  service.Encoding{Enabled: true, Coding: EncodingAuto, Fallback: "ISO-8859-9"},
+			want: "Aı",
+		},
+		{
+			name: "auto default fallback",
+			arg:  []byte{0x80},
+			enc:  service.Encoding{Enabled: true, Coding: EncodingAuto},
+			want: "€",
+		},
+		{
+			name:    "unknown",
+			arg:     []byte("abc"),
+			enc:     service.Encoding{Enabled: true, Coding: "EBCDIC-US"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decode(tt.arg, tt.enc)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Decode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("Decode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMapDecodesStrings(t *testing.T) {
+	plan, err := compileMapping("", []string{"decoded", "plain"}, service.MapType{
+		Enabled: true,
+		Destination: map[string]service.ColumnTypeTemplate{
+			"decoded": {Type: "string", Encoding: service.Encoding{Enabled: true, Coding: EncodingISO88591}},
+			"plain":   {Type: "string"},
+		},
+	}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// drivers can return latin1 bytes as strings, they are decoded instead of sanitized
+	decoded, plain := "caf\xe9", "caf\xe9"
+	row := []any{&decoded, &plain}
+
+	if err := Map(plan, row); err != nil {
+		t.Fatal(err)
+	}
+
+	if row[0] != "café" {
+		t.Errorf("decoded = %q, want %q", row[0], "café")
+	}
+
+	if row[1] != "caf�" {
+		t.Errorf("plain = %q, want %q", row[1], "caf�")
+	}
+}
+
+func TestIterMapEncodedSource(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(`CREATE TABLE users (id INTEGER, name TEXT)`); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.Exec(`INSERT INTO users VALUES (1, ?)`, "caf\xe9"); err != nil {
+		t.Fatal(err)
+	}
+
+	d := &Database{DB: map[string]*Info{"sqlite": {DB: db, DBType: "sqlite3"}}}
+	latin1 := service.Encoding{Enabled: true, Coding: EncodingISO88591}
+
+	// fan-out source keeps the column decoded by a destination as read
+	columns, rows, err := d.IterGet(t.Context(), "sqlite", "SELECT id, name FROM users", nil, service.MapType{
+		Enabled: true,
+		Column:  map[string]service.ColumnType{"name": {Type: "string"}},
+		Destination: map[string]service.ColumnTypeTemplate{
+			"name": {Encoding: latin1},
+		},
+	}, service.LOB{}, service.Snapshot{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var source []any
+	for row, err := range rows {
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if source == nil {
+			source = row
+		}
+	}
+
+	sourceRows := func(yield func([]any, error) bool) {
+		yield(source, nil)
+	}
+
+	mapName := func(colType service.ColumnTypeTemplate, rows iter.Seq2[[]any, error]) any {
+		for row, err := range d.IterMap("sqlite", columns, service.MapType{
+			Enabled:     true,
+			Destination: map[string]service.ColumnTypeTemplate{"name": colType},
+		}, rows) {
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			return row[1]
+		}
+
+		return nil
+	}
+
+	if got := mapName(service.ColumnTypeTemplate{Type: "string", Encoding: latin1}, sourceRows); got != "café" {
+		t.Errorf("decoded = %q, want %q", got, "café")
+	}
+
+	if got := mapName(service.ColumnTypeTemplate{Type: "string"}, sourceRows); got != "caf\uFFFD" {
+		t.Errorf("plain = %q, want %q", got, "caf\uFFFD")
+	}
+}
@@ -5,11 +5,11 @@ import (
 	"fmt"
 	"reflect"
 	"slices"
+	"strings"
 	"time"
 
 	"github.com/shopspring/decimal"
 	"github.com/spf13/cast"
-	"github.com/worldline-go/saz/internal/render"
 	"github.com/worldline-go/saz/internal/service"
 	"github.com/worldline-go/types"
 )
@@ -79,6 +79,22 @@ func GetType(col *sql.ColumnType, mapType service.MapType) any {
 					return new(types.Null[types.Time])
 				}
 				return new(types.Time)
+			case "integer":
+				if colMapType.Nullable {
+					return new(types.Null[int64])
+				}
+				return new(int64)
+			case "boolean":
+				if colMapType.Nullable {
+					return new(types.Null[bool])
+				}
+				return new(bool)
+			case "json":
+				return new(types.RawJSON)
+			case "uuid":
+				return &UUID{mixedEndian: strings.EqualFold(col.DatabaseTypeName(), "UNIQUEIDENTIFIER")}
+			case "bytes":
+				return new([]byte)
 			}
 		}
 	}
@@ -107,8 +123,15 @@ func GetType(col *sql.ColumnType, mapType service.MapType) any {
 	return new(any)
 }
 
-func Map(columnsIndex map[string]int, mapType service.MapType, values []any) error {
+// Map sanitizes string values and applies the compiled destination mapping to the row.
+// Values of columns with an encoding are decoded by the mapping, they are not sanitized before.
+// Sanitized values are replaced, not changed in place, because the row can be shared with other destinations.
+func Map(plan *mappingPlan, values []any) error {
 	for i := range values {
+		if plan.decodes(i) {
+			continue
+		}
+
 		// check if interface is string than sanitize utf8
 		switch v := values[i].(type) {
 		case *string:
@@ -126,74 +149,49 @@ func Map(columnsIndex map[string]int, mapType service.MapType, values []any) err
 			}
 
 			if v.Valid {
-				v.V = SanitizeString(v.V)
-				values[i] = v
+				sanitized := types.NewNull(SanitizeString(v.V))
+				values[i] = &sanitized
 
 				continue
 			}
 		}
 	}
 
-	if err := mapDestination(columnsIndex, mapType, values); err != nil {
+	if err := plan.apply(values); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func mapDestination(columnsIndex map[string]int, mapType service.MapType, result []any) error {
-	if !mapType.Enabled {
+// indirect returns the value behind scanned pointers, nil pointers are returned as nil.
+func indirect(v any) any {
+	if v == nil {
 		return nil
 	}
 
-	for k, colType := range mapType.Destination {
-		if idx, ok := columnsIndex[k]; ok {
-			switch colType.Type {
-			case "string":
-				vStr, err := getAnyString(result[idx], colType)
-				if err != nil {
-					return err
-				}
-
-				if colType.Nullable {
-					result[idx] = vStr
-				} else {
-					result[idx] = vStr.V
-				}
-			case "number":
-				vNum, err := getAnyNumber(result[idx], colType)
-				if err != nil {
-					return err
-				}
-
-				if colType.Nullable {
-					result[idx] = vNum
-				} else {
-					result[idx] = vNum.Decimal
-				}
-			case "date":
-				vDate, err := getAnyDate(result[idx], colType)
-				if err != nil {
-					return err
-				}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer {
+		return v
+	}
 
-				if colType.Nullable {
-					result[idx] = vDate
-				} else {
-					result[idx] = vDate.V
-				}
-			}
-		}
+	if rv.IsNil() {
+		return nil
 	}
 
-	return nil
+	return rv.Elem().Interface()
 }
 
-func getAnyString(v any, t service.ColumnTypeTemplate) (types.Null[string], error) {
+func getAnyString(v any, t *columnMapping) (types.Null[string], error) {
 	switch val := v.(type) {
 	case string:
+		val, err := t.decodeString(val)
+		if err != nil {
+			return types.Null[string]{}, err
+		}
+
 		if t.Template.Enabled {
-			vRendered, err := render.ExecuteWithData(t.Template.Value, val)
+			vRendered, err := t.render(val)
 			if err != nil {
 				return types.Null[string]{}, err
 			}
@@ -201,8 +199,15 @@ func getAnyString(v any, t service.ColumnTypeTemplate) (types.Null[string], erro
 		}
 		return types.NewNull(val), nil
 	case types.Null[string]:
+		if val.Valid {
+			var err error
+			if val.V, err = t.decodeString(val.V); err != nil {
+				return types.Null[string]{}, err
+			}
+		}
+
 		if t.Template.Enabled {
-			vRendered, err := render.ExecuteWithData(t.Template.Value, val.V)
+			vRendered, err := t.render(val.V)
 			if err != nil {
 				return types.Null[string]{}, err
 			}
@@ -210,24 +215,13 @@ func getAnyString(v any, t service.ColumnTypeTemplate) (types.Null[string], erro
 		}
 		return val, nil
 	case []byte:
-		var v string
-		if t.Encoding.Enabled {
-			var err error
-			switch t.Encoding.Coding {
-			case EncodingISO88591:
-				v, err = ConvertISO88591ToUTF8(val)
-				if err != nil {
-					return types.Null[string]{}, err
-				}
-			default:
-				v = string(val)
-			}
-		} else {
-			v = string(val)
+		v, err := t.decode(val)
+		if err != nil {
+			return types.Null[string]{}, err
 		}
 
 		if t.Template.Enabled {
-			vRendered, err := render.ExecuteWithData(t.Template.Value, v)
+			vRendered, err := t.render(v)
 			if err != nil {
 				return types.Null[string]{}, err
 			}
@@ -242,7 +236,7 @@ func getAnyString(v any, t service.ColumnTypeTemplate) (types.Null[string], erro
 	}
 
 	if t.Template.Enabled {
-		vRendered, err := render.ExecuteWithData(t.Template.Value, cast.ToString(v))
+		vRendered, err := t.render(cast.ToString(v))
 		if err != nil {
 			return types.Null[string]{}, err
 		}
@@ -251,11 +245,11 @@ func getAnyString(v any, t service.ColumnTypeTemplate) (types.Null[string], erro
 	return types.NewNull(cast.ToString(v)), nil
 }
 
-func getAnyNumber(v any, t service.ColumnTypeTemplate) (types.NullDecimal, error) {
+func getAnyNumber(v any, t *columnMapping) (types.NullDecimal, error) {
 	switch val := v.(type) {
 	case types.Decimal:
 		if t.Template.Enabled {
-			vRendered, err := render.ExecuteWithData(t.Template.Value, val.String())
+			vRendered, err := t.render(val.String())
 			if err != nil {
 				return types.NullDecimal{}, err
 			}
@@ -268,7 +262,7 @@ func getAnyNumber(v any, t service.ColumnTypeTemplate) (types.NullDecimal, error
 		return types.NullDecimal{Decimal: val, Valid: true}, nil
 	case types.NullDecimal:
 		if t.Template.Enabled {
-			vRendered, err := render.ExecuteWithData(t.Template.Value, val.Decimal.String())
+			vRendered, err := t.render(val.Decimal.String())
 			if err != nil {
 				return types.NullDecimal{}, err
 			}
@@ -286,7 +280,7 @@ func getAnyNumber(v any, t service.ColumnTypeTemplate) (types.NullDecimal, error
 	}
 
 	if t.Template.Enabled {
-		vRendered, err := render.ExecuteWithData(t.Template.Value, cast.ToString(v))
+		vRendered, err := t.render(cast.ToString(v))
 		if err != nil {
 			return types.NullDecimal{}, err
 		}
@@ -303,142 +297,69 @@ func getAnyNumber(v any, t service.ColumnTypeTemplate) (types.NullDecimal, error
 	return types.NullDecimal{Decimal: decimalVal, Valid: true}, nil
 }
 
-func getAnyDate(v any, t service.ColumnTypeTemplate) (types.Null[types.Time], error) {
+func getAnyDate(v any, t *columnMapping) (types.Null[types.Time], error) {
+	var (
+		vStr   string
+		vTime  time.Time
+		isTime bool
+	)
+
 	switch val := v.(type) {
 	case time.Time:
-		if t.Template.Enabled {
-			vRendered, err := render.ExecuteWithData(t.Template.Value, val.Format(time.RFC3339))
-			if err != nil {
-				return types.Null[types.Time]{}, err
-			}
-
-			var parsedTime types.Time
-			if err := parsedTime.Parse(string(vRendered)); err != nil {
-				return types.Null[types.Time]{}, err
-			}
-			return types.NewTimeNullWithValid(parsedTime.Time, true), nil
-		}
-		return types.NewTimeNullWithValid(val, true), nil
+		vTime, isTime = val, true
 	case types.Time:
-		if t.Template.Enabled {
-			vRendered, err := render.ExecuteWithData(t.Template.Value, val.String())
-			if err != nil {
-				return types.Null[types.Time]{}, err
-			}
-
-			var parsedTime types.Time
-			if err := parsedTime.Parse(string(vRendered)); err != nil {
-				return types.Null[types.Time]{}, err
-			}
-			return types.NewTimeNullWithValid(parsedTime.Time, true), nil
-		}
-		return types.NewTimeNullWithValid(val.Time, true), nil
+		vTime, isTime = val.Time, true
 	case types.Null[types.Time]:
-		if t.Template.Enabled {
-			vRendered, err := render.ExecuteWithData(t.Template.Value, val.V.String())
-			if err != nil {
-				return types.Null[types.Time]{}, err
-			}
-
-			var parsedTime types.Time
-			if err := parsedTime.Parse(string(vRendered)); err != nil {
-				return types.Null[types.Time]{}, err
-			}
-			return types.NewTimeNullWithValid(parsedTime.Time, val.Valid), nil
-		}
-		return val, nil
-	case string:
-		var parsedTime types.Time
-		if t.Template.Enabled {
-			vRendered, err := render.ExecuteWithData(t.Template.Value, val)
-			if err != nil {
-				return types.Null[types.Time]{}, err
-			}
-
-			if err := parsedTime.Parse(string(vRendered)); err != nil {
-				return types.Null[types.Time]{}, err
-			}
-			return types.NewTimeNullWithValid(parsedTime.Time, true), nil
+		if !val.Valid {
+			return val, nil
 		}
 
-		if err := parsedTime.Parse(val); err != nil {
-			return types.Null[types.Time]{}, err
-		}
-		return types.NewTimeNullWithValid(parsedTime.Time, true), nil
+		vTime, isTime = val.V.Time, true
+	case string:
+		vStr = val
 	case types.Null[string]:
-		if !val.Valid {
-			if t.Nullable {
-				return types.NewTimeNullWithValid(time.Time{}, false), nil
-			}
+		if !val.Valid && t.Nullable {
+			return types.NewTimeNullWithValid(time.Time{}, false), nil
 		}
 
-		var parsedTime types.Time
-		if t.Template.Enabled {
-			vRendered, err := render.ExecuteWithData(t.Template.Value, val.V)
-			if err != nil {
-				return types.Null[types.Time]{}, err
-			}
-			if err := parsedTime.Parse(string(vRendered)); err != nil {
-				return types.Null[types.Time]{}, err
-			}
-			return types.NewTimeNullWithValid(parsedTime.Time, val.Valid), nil
-		}
-		if err := parsedTime.Parse(val.V); err != nil {
-			return types.Null[types.Time]{}, err
-		}
-		return types.NewTimeNullWithValid(parsedTime.Time, val.Valid), nil
+		vStr = val.V
 	case []byte:
-		var v string
-		if t.Encoding.Enabled {
-			var err error
-			switch t.Encoding.Coding {
-			case EncodingISO88591:
-				v, err = ConvertISO88591ToUTF8(val)
-				if err != nil {
-					return types.Null[types.Time]{}, err
-				}
-			default:
-				v = string(val)
-			}
-		} else {
-			v = string(val)
-		}
-
-		var parsedTime types.Time
-		if t.Template.Enabled {
-			vRendered, err := render.ExecuteWithData(t.Template.Value, v)
-			if err != nil {
-				return types.Null[types.Time]{}, err
-			}
-			if err := parsedTime.Parse(string(vRendered)); err != nil {
-				return types.Null[types.Time]{}, err
-			}
-			return types.NewTimeNullWithValid(parsedTime.Time, true), nil
-		}
-		if err := parsedTime.Parse(v); err != nil {
+		decoded, err := t.decode(val)
+		if err != nil {
 			return types.Null[types.Time]{}, err
 		}
-		return types.NewTimeNullWithValid(parsedTime.Time, true), nil
+
+		vStr = decoded
 	case nil:
 		if t.Nullable {
 			return types.NewTimeNullWithValid(time.Time{}, false), nil
 		}
+	default:
+		// try to convert to string and parse
+		vStr = cast.ToString(v)
 	}
 
-	// Default case: try to convert to string and parse
-	var parsedTime types.Time
-	if t.Template.Enabled {
-		vRendered, err := render.ExecuteWithData(t.Template.Value, cast.ToString(v))
+	if isTime && t.Template.Enabled {
+		vStr, isTime = vTime.Format(time.RFC3339), false
+	}
+
+	if !isTime {
+		var err error
+		vStr, err = t.renderValue(vStr)
 		if err != nil {
 			return types.Null[types.Time]{}, err
 		}
-		if err := parsedTime.Parse(string(vRendered)); err != nil {
+
+		vTime, err = parseDate(vStr, t.Date)
+		if err != nil {
 			return types.Null[types.Time]{}, err
 		}
-		return types.NewTimeNullWithValid(parsedTime.Time, true), nil
 	}
-	if err := parsedTime.Parse(cast.ToString(v)); err != nil {
+
+	vTime, err := convertDate(vTime, t.Date, isTime)
+	if err != nil {
 		return types.Null[types.Time]{}, err
 	}
-	return types.NewTimeNullWithValid(parsedTime.Time, true), nil
+
+	return types.NewTimeNullWithValid(vTime, true), nil
 }
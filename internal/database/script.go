@@ -0,0 +1,172 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/worldline-go/saz/internal/service"
+)
+
+var (
+	reFirstKeyword = regexp.MustCompile(`^\(*\s*([A-Za-z]+)`)
+	reReturning    = regexp.MustCompile(`(?i)\bRETURNING\b`)
+)
+
+var rowKeywords = map[string]struct{}{
+	"SELECT":   {},
+	"WITH":     {},
+	"VALUES":   {},
+	"SHOW":     {},
+	"EXPLAIN":  {},
+	"DESCRIBE": {},
+	"DESC":     {},
+	"PRAGMA":   {},
+	"TABLE":    {},
+	"CALL":     {},
+	"EXEC":     {},
+	"EXECUTE":  {},
+}
+
+// Script runs the statements of script in order on one connection, session state like temporary tables is kept between statements.
+// Result lists the statements and Results returns every result set.
+func (d *Database) Script(ctx context.Context, name, script string, params map[string]any, limit int64, typed bool) (service.Result, error) {
+	dbConn, err := d.conn(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	defer dbConn.release()
+
+	statements := SplitStatements(dbConn.DBType, script)
+	if len(statements) == 0 {
+		return nil, fmt.Errorf("script has no statements; %w", service.ErrBadRequest)
+	}
+
+	if err := checkReadOnly(name, dbConn, script); err != nil {
+		return nil, err
+	}
+
+	conn, err := dbConn.DB.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get connection of database %s: %w", name, err)
+	}
+	defer conn.Close()
+
+	db, release, err := session(ctx, dbConn, conn)
+	if err != nil {
+		return nil, fmt.Errorf("database %s: %w", name, err)
+	}
+	defer release()
+
+	start := time.Now()
+
+	result := &Result{
+		columns: []string{"statement", "result_sets", "rows", "rows_affected", "duration"},
+		rows:    [][]any{},
+	}
+
+	for i, stmt := range statements {
+		stmtStart := time.Now()
+		query, args := BindNamed(dbConn.DBType, stmt, params)
+
+		var (
+			sets         []*Result
+			rowsAffected int64
+		)
+
+		if returnsRows(dbConn.DBType, query) {
+			sets, err = queryResultSets(ctx, db, query, args, limit, typed)
+			if err != nil {
+				return nil, fmt.Errorf("statement %d: %w", i+1, err)
+			}
+		} else {
+			execResult, err := db.ExecContext(ctx, query, args...)
+			if err != nil {
+				return nil, fmt.Errorf("statement %d: %w", i+1, err)
+			}
+
+			rowsAffected, _ = execResult.RowsAffected()
+			sets = append(sets, &Result{
+				columns:      []string{"status"},
+				rows:         [][]any{{"success"}},
+				rowsAffected: rowsAffected,
+			})
+		}
+
+		duration := time.Since(stmtStart)
+
+		var rowCount int
+		for _, set := range sets {
+			set.duration = duration
+			rowCount += len(set.rows)
+			result.results = append(result.results, set)
+		}
+
+		result.rowsAffected += rowsAffected
+		result.rows = append(result.rows, []any{
+			i + 1, len(sets), rowCount, rowsAffected, duration.Truncate(time.Microsecond).String(),
+		})
+	}
+
+	result.duration = time.Since(start)
+
+	return result, nil
+}
+
+func queryResultSets(ctx context.Context, conn queryer, query string, args []any, limit int64, typed bool) ([]*Result, error) {
+	rowsIter, err := conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rowsIter.Close()
+
+	var sets []*Result
+	for {
+		set, err := readResultSet(rowsIter, limit, typed)
+		if err != nil {
+			return nil, err
+		}
+
+		// statements without rows in a batch have no columns
+		if len(set.columns) > 0 {
+			sets = append(sets, set)
+		}
+
+		if !rowsIter.NextResultSet() {
+			break
+		}
+	}
+
+	if err := rowsIter.Err(); err != nil {
+		return nil, fmt.Errorf("iterate rows: %w", err)
+	}
+
+	return sets, nil
+}
+
+// returnsRows reports whether the statement is run as a query instead of exec.
+// SQL Server batches can mix statements so they are always queries.
+func returnsRows(dbType, stmt string) bool {
+	if dbType == "sqlserver" {
+		return true
+	}
+
+	m := reFirstKeyword.FindStringSubmatch(stripLeadingComments(stmt))
+	if m == nil {
+		return false
+	}
+
+	keyword := strings.ToUpper(m[1])
+	if keyword == "CALL" && dbType == "godror" {
+		return false
+	}
+
+	if _, ok := rowKeywords[keyword]; ok {
+		return true
+	}
+
+	// RETURNING of Oracle needs out binds
+	return dbType != "godror" && reReturning.MatchString(stmt)
+}
@@ -0,0 +1,178 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/worldline-go/saz/internal/config"
+)
+
+var schemaName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_$#]*$`)
+
+// sessionStatements returns the statements run on every new connection, the default schema is set first.
+func sessionStatements(cfg config.Database) ([]string, error) {
+	var statements []string
+
+	if cfg.DBSchema != "" && schemaSupported(cfg.DBType) {
+		stmt, err := schemaStatement(cfg.DBType, cfg.DBSchema)
+		if err != nil {
+			return nil, err
+		}
+
+		statements = append(statements, stmt)
+	}
+
+	for _, stmt := range cfg.InitStatements {
+		if stmt = strings.TrimSpace(stmt); stmt != "" {
+			statements = append(statements, stmt)
+		}
+	}
+
+	return statements, nil
+}
+
+// schemaSupported reports whether the database has a session schema, db_schema of others is ignored.
+func schemaSupported(dbType string) bool {
+	switch dbType {
+	case "pgx", "postgres", "godror", "mysql":
+		return true
+	}
+
+	return false
+}
+
+// schemaStatement returns the statement setting the default schema of the session.
+// PostgreSQL schema can be a comma separated search path with "$user".
+func schemaStatement(dbType, schema string) (string, error) {
+	names := []string{schema}
+	if dbType == "pgx" || dbType == "postgres" {
+		names = strings.Split(schema, ",")
+	}
+
+	for i, name := range names {
+		names[i] = strings.TrimSpace(name)
+		if (dbType == "pgx" || dbType == "postgres") && (names[i] == "$user" || names[i] == `"$user"`) {
+			names[i] = "$user"
+
+			continue
+		}
+
+		if !schemaName.MatchString(names[i]) {
+			return "", fmt.Errorf("invalid db_schema %q", schema)
+		}
+	}
+
+	switch dbType {
+	case "pgx", "postgres":
+		for i, name := range names {
+			names[i] = `"` + name + `"`
+		}
+
+		return "SET search_path TO " + strings.Join(names, ", "), nil
+	case "godror":
+		// unquoted to match upper case names of Oracle
+		return "ALTER SESSION SET CURRENT_SCHEMA = " + schema, nil
+	case "mysql":
+		return "USE `" + schema + "`", nil
+	default:
+		return "", fmt.Errorf("db_schema is not supported for %s, use init_statements", dbType)
+	}
+}
+
+// sessionConnector runs the statements on every new connection of the pool.
+type sessionConnector struct {
+	driver.Connector
+
+	statements []string
+}
+
+// newSessionConnector wraps the connector of the driver of the type.
+func newSessionConnector(dbType, datasource string, statements []string) (driver.Connector, error) {
+	db, err := sql.Open(dbType, datasource)
+	if err != nil {
+		return nil, err
+	}
+
+	drv := db.Driver()
+	_ = db.Close()
+
+	var connector driver.Connector = dsnConnector{datasource: datasource, driver: drv}
+	if drvCtx, ok := drv.(driver.DriverContext); ok {
+		connector, err = drvCtx.OpenConnector(datasource)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &sessionConnector{Connector: connector, statements: statements}, nil
+}
+
+func (c *sessionConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, stmt := range c.statements {
+		if err := execSession(ctx, conn, stmt); err != nil {
+			return nil, errors.Join(fmt.Errorf("session statement %q: %w", stmt, err), conn.Close())
+		}
+	}
+
+	return conn, nil
+}
+
+func execSession(ctx context.Context, conn driver.Conn, stmt string) error {
+	if execer, ok := conn.(driver.ExecerContext); ok {
+		// ErrSkip falls back to a prepared statement
+		_, err := execer.ExecContext(ctx, stmt, nil)
+		if !errors.Is(err, driver.ErrSkip) {
+			return err
+		}
+	}
+
+	var (
+		prepared driver.Stmt
+		err      error
+	)
+
+	if preparer, ok := conn.(driver.ConnPrepareContext); ok {
+		prepared, err = preparer.PrepareContext(ctx, stmt)
+	} else {
+		prepared, err = conn.Prepare(stmt)
+	}
+
+	if err != nil {
+		return err
+	}
+	defer prepared.Close()
+
+	if execer, ok := prepared.(driver.StmtExecContext); ok {
+		_, err = execer.ExecContext(ctx, nil)
+
+		return err
+	}
+
+	_, err = prepared.Exec(nil)
+
+	return err
+}
+
+// dsnConnector is the connector of drivers without their own, like sql.Open does.
+type dsnConnector struct {
+	datasource string
+	driver     driver.Driver
+}
+
+func (c dsnConnector) Connect(_ context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.datasource)
+}
+
+func (c dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
@@ -0,0 +1,111 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/worldline-go/saz/internal/config"
+)
+
+func TestSessionStatements(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.Database
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "empty",
+			cfg:  config.Database{DBType: "pgx"},
+		},
+		{
+			name: "postgres search path",
+			cfg:  config.Database{DBType: "pgx", DBSchema: "sales, public", InitStatements: []string{"SET TIME ZONE 'UTC'", " "}},
+			want: []string{`SET search_path TO "sales", "public"`, "SET TIME ZONE 'UTC'"},
+		},
+		{
+			name: "oracle",
+			cfg:  config.Database{DBType: "godror", DBSchema: "HR", InitStatements: []string{"ALTER SESSION SET NLS_DATE_FORMAT = 'YYYY-MM-DD'"}},
+			want: []string{"ALTER SESSION SET CURRENT_SCHEMA = HR", "ALTER SESSION SET NLS_DATE_FORMAT = 'YYYY-MM-DD'"},
+		},
+		{
+			name: "mysql",
+			cfg:  config.Database{DBType: "mysql", DBSchema: "shop"},
+			want: []string{"USE `shop`"},
+		},
+		{
+			name:    "injection",
+			cfg:     config.Database{DBType: "pgx", DBSchema: `public"; DROP TABLE x; --`},
+			wantErr: true,
+		},
+		{
+			name: "postgres user schema",
+			cfg:  config.Database{DBType: "pgx", DBSchema: `"$user", public`},
+			want: []string{`SET search_path TO "$user", "public"`},
+		},
+		{
+			name: "unsupported is ignored",
+			cfg:  config.Database{DBType: "sqlserver", DBSchema: "dbo"},
+		},
+		{
+			name:    "user schema only in postgres",
+			cfg:     config.Database{DBType: "mysql", DBSchema: "$user"},
+			wantErr: true,
+		},
+		{
+			name: "unsupported schema with init statements",
+			cfg:  config.Database{DBType: "sqlite3", InitStatements: []string{"PRAGMA foreign_keys = ON"}},
+			want: []string{"PRAGMA foreign_keys = ON"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := sessionStatements(tt.cfg)
+			if tt.wantErr {
+				require.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSessionConnector(t *testing.T) {
+	db, err := open(t.Context(), config.Database{
+		DBType:       "sqlite3",
+		DBDatasource: "file:" + filepath.Join(t.TempDir(), "saz.db"),
+		MaxOpenConns: 2,
+		InitStatements: []string{
+			"PRAGMA foreign_keys = ON",
+			"CREATE TEMP TABLE session_init (id INTEGER)",
+		},
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	// every connection of the pool runs the statements
+	for range 2 {
+		conn, err := db.Conn(t.Context())
+		require.NoError(t, err)
+		defer conn.Close()
+
+		var foreignKeys int
+		require.NoError(t, conn.QueryRowContext(t.Context(), "PRAGMA foreign_keys").Scan(&foreignKeys))
+		require.Equal(t, 1, foreignKeys)
+
+		_, err = conn.ExecContext(t.Context(), "INSERT INTO session_init VALUES (1)")
+		require.NoError(t, err)
+	}
+
+	_, err = open(t.Context(), config.Database{
+		DBType:         "sqlite3",
+		DBDatasource:   ":memory:",
+		InitStatements: []string{"SELECT * FROM missing"},
+	})
+	require.ErrorContains(t, err, "SELECT * FROM missing")
+}
@@ -0,0 +1,295 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"sync"
+
+	"github.com/rakunlabs/logi"
+	"github.com/worldline-go/saz/internal/service"
+)
+
+var rePGSnapshotID = regexp.MustCompile(`^[0-9A-Fa-f]+(-[0-9A-Fa-f]+)+$`)
+
+type (
+	snapshotKey struct{}
+	exportKey   struct{}
+)
+
+// snapshotSession holds snapshot transactions of databases until the session is released.
+type snapshotSession struct {
+	mu  sync.Mutex
+	txs map[string]*snapshotTx
+}
+
+// snapshotExport holds the transactions exporting the snapshot of PostgreSQL databases until it is released.
+// Snapshot reads in it open their own transaction importing the snapshot, so they can outlive the export.
+type snapshotExport struct {
+	mu  sync.Mutex
+	txs map[string]*snapshotTx
+}
+
+// snapshotTx is a read only transaction on a dedicated connection, reads are serialized.
+type snapshotTx struct {
+	mu        sync.Mutex
+	conn      *sql.Conn
+	tx        *sql.Tx
+	flashback bool
+	// id is the exported snapshot of PostgreSQL.
+	id string
+}
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+func (d *Database) WithSnapshot(ctx context.Context) (context.Context, func()) {
+	session := &snapshotSession{txs: make(map[string]*snapshotTx)}
+
+	return context.WithValue(ctx, snapshotKey{}, session), session.close
+}
+
+func (d *Database) WithExportedSnapshot(ctx context.Context) (context.Context, func()) {
+	export := &snapshotExport{txs: make(map[string]*snapshotTx)}
+
+	return context.WithValue(ctx, exportKey{}, export), export.close
+}
+
+// snapshotID returns the exported snapshot of the database, the first read of the database exports it.
+func (e *snapshotExport) snapshotID(ctx context.Context, name string, dbConn *Info, snapshot service.Snapshot) (string, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	stx, ok := e.txs[name]
+	if !ok {
+		var err error
+		// export outlives the cell, transaction is ended by release of the export
+		stx, err = openSnapshot(context.WithoutCancel(ctx), dbConn, snapshot)
+		if err != nil {
+			return "", err
+		}
+
+		e.txs[name] = stx
+	}
+
+	return stx.id, nil
+}
+
+func (e *snapshotExport) close() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for name, stx := range e.txs {
+		if err := stx.close(); err != nil {
+			slog.Error("close exported snapshot", "name", name, "error", err)
+		}
+	}
+
+	clear(e.txs)
+}
+
+func (s *snapshotSession) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for name, stx := range s.txs {
+		if err := stx.close(); err != nil {
+			slog.Error("close snapshot", "name", name, "error", err)
+		}
+	}
+
+	clear(s.txs)
+}
+
+// reader returns where the query runs and the release function to call after reading.
+// Without snapshot it is the connection pool or a read only transaction for read only databases, with snapshot it is the transaction of the session
+// or a transaction for this read only when there is no session.
+func (d *Database) reader(ctx context.Context, name string, dbConn *Info, snapshot service.Snapshot) (queryer, func(), error) {
+	if !snapshot.Enabled {
+		db, release, err := session(ctx, dbConn, dbConn.DB)
+		if err != nil {
+			return nil, nil, fmt.Errorf("database %s: %w", name, err)
+		}
+
+		return db, release, nil
+	}
+
+	// reads of PostgreSQL import the exported snapshot, other databases read in their own transaction
+	if export, _ := ctx.Value(exportKey{}).(*snapshotExport); export != nil && dbConn.DBType == "pgx" && snapshot.ID == "" {
+		id, err := export.snapshotID(ctx, name, dbConn, snapshot)
+		if err != nil {
+			return nil, nil, fmt.Errorf("export snapshot on database %s: %w", name, err)
+		}
+
+		snapshot.ID = id
+	}
+
+	session, _ := ctx.Value(snapshotKey{}).(*snapshotSession)
+	if session == nil {
+		stx, err := openSnapshot(ctx, dbConn, snapshot)
+		if err != nil {
+			return nil, nil, fmt.Errorf("open snapshot on database %s: %w", name, err)
+		}
+
+		return stx.tx, func() {
+			if err := stx.close(); err != nil {
+				logi.Ctx(ctx).Error("close snapshot", slog.String("name", name), slog.String("error", err.Error()))
+			}
+		}, nil
+	}
+
+	session.mu.Lock()
+	stx, ok := session.txs[name]
+	if !ok {
+		var err error
+		// session outlives the cell, transaction is ended by release of the session
+		stx, err = openSnapshot(context.WithoutCancel(ctx), dbConn, snapshot)
+		if err != nil {
+			session.mu.Unlock()
+
+			return nil, nil, fmt.Errorf("open snapshot on database %s: %w", name, err)
+		}
+
+		session.txs[name] = stx
+	}
+	session.mu.Unlock()
+
+	stx.mu.Lock()
+
+	return stx.tx, stx.mu.Unlock, nil
+}
+
+func openSnapshot(ctx context.Context, dbConn *Info, snapshot service.Snapshot) (_ *snapshotTx, err error) {
+	opts, err := snapshotTxOptions(dbConn.DBType, snapshot.Isolation)
+	if err != nil {
+		return nil, err
+	}
+
+	if snapshot.ID != "" {
+		if dbConn.DBType != "pgx" {
+			return nil, fmt.Errorf("snapshot id is only for pgx; %w", service.ErrBadRequest)
+		}
+
+		if !rePGSnapshotID.MatchString(snapshot.ID) {
+			return nil, fmt.Errorf("invalid snapshot id %q; %w", snapshot.ID, service.ErrBadRequest)
+		}
+	}
+
+	var scn uint64
+	if snapshot.SCN != "" {
+		if dbConn.DBType != "godror" {
+			return nil, fmt.Errorf("scn is only for godror; %w", service.ErrBadRequest)
+		}
+
+		scn, err = strconv.ParseUint(snapshot.SCN, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid scn %q; %w", snapshot.SCN, service.ErrBadRequest)
+		}
+
+		// flashback session is already consistent, transaction statements are not allowed in it
+		opts = &sql.TxOptions{}
+	}
+
+	conn, err := dbConn.DB.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get connection: %w", err)
+	}
+
+	stx := &snapshotTx{conn: conn}
+	defer func() {
+		if err != nil {
+			_ = stx.close()
+		}
+	}()
+
+	if snapshot.SCN != "" {
+		if _, err := conn.ExecContext(ctx, "BEGIN DBMS_FLASHBACK.ENABLE_AT_SYSTEM_CHANGE_NUMBER(:1); END;", scn); err != nil {
+			return nil, fmt.Errorf("enable flashback: %w", err)
+		}
+
+		stx.flashback = true
+	}
+
+	stx.tx, err = conn.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+
+	if dbConn.DBType == "pgx" {
+		if snapshot.ID != "" {
+			// snapshot id is validated, SET TRANSACTION does not accept parameters
+			if _, err := stx.tx.ExecContext(ctx, "SET TRANSACTION SNAPSHOT '"+snapshot.ID+"'"); err != nil {
+				return nil, fmt.Errorf("import snapshot %s: %w", snapshot.ID, err)
+			}
+		}
+
+		if err := stx.tx.QueryRowContext(ctx, "SELECT pg_export_snapshot()").Scan(&stx.id); err != nil {
+			return nil, fmt.Errorf("export snapshot: %w", err)
+		}
+
+		logi.Ctx(ctx).Info("exported snapshot", slog.String("snapshot_id", stx.id))
+	}
+
+	return stx, nil
+}
+
+func (s *snapshotTx) close() error {
+	var err error
+	if s.tx != nil {
+		// nothing is written, rollback only ends the transaction
+		if errRollback := s.tx.Rollback(); errRollback != nil && !errors.Is(errRollback, sql.ErrTxDone) {
+			err = fmt.Errorf("rollback: %w", errRollback)
+		}
+	}
+
+	if s.flashback {
+		if _, errDisable := s.conn.ExecContext(context.Background(), "BEGIN DBMS_FLASHBACK.DISABLE; END;"); errDisable != nil && err == nil {
+			err = fmt.Errorf("disable flashback: %w", errDisable)
+		}
+	}
+
+	if errClose := s.conn.Close(); errClose != nil && err == nil {
+		err = fmt.Errorf("close connection: %w", errClose)
+	}
+
+	return err
+}
+
+// snapshotTxOptions returns the transaction options supported by the driver.
+func snapshotTxOptions(dbType, isolation string) (*sql.TxOptions, error) {
+	var level sql.IsolationLevel
+
+	switch isolation {
+	case "", service.IsolationRepeatableRead:
+		level = sql.LevelRepeatableRead
+	case service.IsolationSerializable:
+		level = sql.LevelSerializable
+	case service.IsolationSnapshot:
+		if dbType != "sqlserver" {
+			return nil, fmt.Errorf("isolation %s is only for sqlserver; %w", isolation, service.ErrBadRequest)
+		}
+
+		level = sql.LevelSnapshot
+	default:
+		return nil, fmt.Errorf("unknown isolation %q; %w", isolation, service.ErrBadRequest)
+	}
+
+	switch dbType {
+	case "godror":
+		// read only transaction of Oracle is consistent for all queries, isolation cannot be combined
+		return &sql.TxOptions{ReadOnly: true}, nil
+	case "sqlserver":
+		// read only transactions are not supported by the driver
+		return &sql.TxOptions{Isolation: level}, nil
+	case "sqlite3":
+		// read transaction of sqlite is already serializable
+		return &sql.TxOptions{}, nil
+	}
+
+	return &sql.TxOptions{Isolation: level, ReadOnly: true}, nil
+}
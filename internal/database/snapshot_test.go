@@ -0,0 +1,73 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/worldline-go/saz/internal/service"
+)
+
+func TestSnapshot(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:"+filepath.Join(t.TempDir(), "snapshot.db")+"?_journal_mode=WAL")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	_, err = db.Exec("CREATE TABLE events (id INTEGER)")
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO events VALUES (1)")
+	require.NoError(t, err)
+
+	d := &Database{DB: map[string]*Info{"sqlite": {DB: db, DBType: "sqlite3"}}}
+	snapshot := service.Snapshot{Enabled: true}
+
+	ctx, release := d.WithSnapshot(t.Context())
+
+	query := func(ctx context.Context, snapshot service.Snapshot) any {
+		result, err := d.Query(ctx, "sqlite", "SELECT count(*) FROM events", nil, 0, false, snapshot)
+		require.NoError(t, err)
+
+		return result.Rows()[0][0]
+	}
+
+	require.Equal(t, "1", query(ctx, snapshot))
+
+	_, err = db.Exec("INSERT INTO events VALUES (2)")
+	require.NoError(t, err)
+
+	// snapshot of the session still sees the first state
+	require.Equal(t, "1", query(ctx, snapshot))
+
+	_, iterGet, err := d.IterGet(ctx, "sqlite", "SELECT id FROM events", nil, service.MapType{}, service.LOB{}, snapshot)
+	require.NoError(t, err)
+
+	var rows int
+	for row, err := range iterGet {
+		require.NoError(t, err)
+
+		if len(row) > 0 {
+			rows++
+		}
+	}
+
+	require.Equal(t, 1, rows)
+
+	// reads without snapshot see the current state
+	require.Equal(t, "2", query(ctx, service.Snapshot{}))
+
+	release()
+
+	// snapshot without session is for a single read
+	require.Equal(t, "2", query(t.Context(), snapshot))
+
+	// only PostgreSQL snapshots are exported, others read in their own transaction
+	ctxExport, releaseExport := d.WithExportedSnapshot(t.Context())
+	require.Equal(t, "2", query(ctxExport, snapshot))
+	require.Empty(t, ctxExport.Value(exportKey{}).(*snapshotExport).txs)
+	releaseExport()
+
+	_, err = d.Query(t.Context(), "sqlite", "SELECT 1", nil, 0, false, service.Snapshot{Enabled: true, SCN: "1"})
+	require.ErrorIs(t, err, service.ErrBadRequest)
+}
@@ -0,0 +1,184 @@
+package database
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	reGOBatch    = regexp.MustCompile(`(?i)^\s*GO(?:\s+(\d+))?\s*$`)
+	reDelimiter  = regexp.MustCompile(`(?i)^\s*DELIMITER\s+(\S+)\s*$`)
+	rePLSQLBlock = regexp.MustCompile(`(?is)^(DECLARE|BEGIN|CREATE\s+(OR\s+REPLACE\s+)?((NON)?EDITIONABLE\s+)?(PROCEDURE|FUNCTION|PACKAGE|TRIGGER|TYPE\s+BODY|LIBRARY))\b`)
+)
+
+// SplitStatements splits a script to statements of the database dialect.
+//   - sqlserver: batches separated with GO lines, GO n repeats the batch
+//   - godror: PL/SQL blocks end with a / line, other statements with ; or /
+//   - mysql: DELIMITER lines change the statement terminator
+//
+// Strings, quoted identifiers, comments and PostgreSQL dollar quoted bodies are not split.
+func SplitStatements(dbType, script string) []string {
+	if dbType == "sqlserver" {
+		return splitBatches(script)
+	}
+
+	var (
+		statements []string
+		current    strings.Builder
+		delimiter  = ";"
+		lineStart  = true
+	)
+
+	flush := func() {
+		if stmt := strings.TrimSpace(current.String()); !isBlankStatement(stmt) {
+			statements = append(statements, stmt)
+		}
+
+		current.Reset()
+	}
+
+	for i := 0; i < len(script); {
+		if lineStart {
+			line, next := nextLine(script, i)
+
+			if dbType == "mysql" {
+				if m := reDelimiter.FindStringSubmatch(line); m != nil {
+					flush()
+					delimiter = m[1]
+					i, lineStart = next, true
+
+					continue
+				}
+			}
+
+			if dbType == "godror" && strings.TrimSpace(line) == "/" {
+				flush()
+				i, lineStart = next, true
+
+				continue
+			}
+		}
+
+		c := script[i]
+		lineStart = c == '\n'
+
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			end := skipQuoted(script, i, c, dbType == "mysql" && c != '`')
+			current.WriteString(script[i:end])
+			i = end
+		case c == '-' && strings.HasPrefix(script[i:], "--"), c == '#' && dbType == "mysql":
+			_, next := nextLine(script, i)
+			current.WriteString(script[i:next])
+			i, lineStart = next, true
+		case c == '/' && strings.HasPrefix(script[i:], "/*"):
+			end := strings.Index(script[i+2:], "*/")
+			if end < 0 {
+				end = len(script)
+			} else {
+				end += i + 4
+			}
+
+			current.WriteString(script[i:end])
+			i = end
+		case c == '$' && (dbType == "pgx" || dbType == "postgres"):
+			end := skipDollarQuoted(script, i)
+			current.WriteString(script[i:end])
+			i = end
+		case strings.HasPrefix(script[i:], delimiter):
+			if dbType == "godror" && rePLSQLBlock.MatchString(stripLeadingComments(current.String())) {
+				// PL/SQL block continues until the / line
+				current.WriteByte(c)
+				i++
+
+				continue
+			}
+
+			flush()
+			i += len(delimiter)
+		default:
+			current.WriteByte(c)
+			i++
+		}
+	}
+
+	flush()
+
+	return statements
+}
+
+// splitBatches splits T-SQL script with GO lines.
+func splitBatches(script string) []string {
+	var (
+		batches []string
+		current strings.Builder
+	)
+
+	for i := 0; i < len(script); {
+		line, next := nextLine(script, i)
+		i = next
+
+		m := reGOBatch.FindStringSubmatch(line)
+		if m == nil {
+			current.WriteString(line)
+			current.WriteByte('\n')
+
+			continue
+		}
+
+		count := 1
+		if m[1] != "" {
+			count, _ = strconv.Atoi(m[1])
+		}
+
+		if batch := strings.TrimSpace(current.String()); !isBlankStatement(batch) {
+			for range count {
+				batches = append(batches, batch)
+			}
+		}
+
+		current.Reset()
+	}
+
+	if batch := strings.TrimSpace(current.String()); !isBlankStatement(batch) {
+		batches = append(batches, batch)
+	}
+
+	return batches
+}
+
+// nextLine returns the line starting at i without newline and the index of the next line.
+func nextLine(s string, i int) (string, int) {
+	end := strings.IndexByte(s[i:], '\n')
+	if end < 0 {
+		return s[i:], len(s)
+	}
+
+	return s[i : i+end], i + end + 1
+}
+
+func stripLeadingComments(s string) string {
+	for {
+		s = strings.TrimSpace(s)
+
+		switch {
+		case strings.HasPrefix(s, "--"):
+			_, next := nextLine(s, 0)
+			s = s[next:]
+		case strings.HasPrefix(s, "/*"):
+			end := strings.Index(s, "*/")
+			if end < 0 {
+				return ""
+			}
+
+			s = s[end+2:]
+		default:
+			return s
+		}
+	}
+}
+
+func isBlankStatement(s string) bool {
+	return stripLeadingComments(s) == ""
+}
@@ -0,0 +1,95 @@
+package database
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name   string
+		dbType string
+		script string
+		want   []string
+	}{
+		{
+			name:   "semicolons",
+			dbType: "sqlite3",
+			script: "CREATE TABLE a (v TEXT);\nINSERT INTO a VALUES ('x;y');\n-- done;\nSELECT * FROM a;",
+			want:   []string{"CREATE TABLE a (v TEXT)", "INSERT INTO a VALUES ('x;y')", "-- done;\nSELECT * FROM a"},
+		},
+		{
+			name:   "comments only",
+			dbType: "pgx",
+			script: "SELECT 1; /* trailing; */ -- end\n",
+			want:   []string{"SELECT 1"},
+		},
+		{
+			name:   "postgres dollar quoted body",
+			dbType: "pgx",
+			script: "CREATE FUNCTION f() RETURNS int AS $$ BEGIN RETURN 1; END; $$ LANGUAGE plpgsql;\nSELECT f();",
+			want:   []string{"CREATE FUNCTION f() RETURNS int AS $$ BEGIN RETURN 1; END; $$ LANGUAGE plpgsql", "SELECT f()"},
+		},
+		{
+			name:   "sqlserver batches",
+			dbType: "sqlserver",
+			script: "CREATE TABLE #t (v INT);\nINSERT INTO #t VALUES (1);\ngo\nSELECT * FROM #t; SELECT 2;\nGO 2\n",
+			want: []string{
+				"CREATE TABLE #t (v INT);\nINSERT INTO #t VALUES (1);",
+				"SELECT * FROM #t; SELECT 2;",
+				"SELECT * FROM #t; SELECT 2;",
+			},
+		},
+		{
+			name:   "oracle plsql block",
+			dbType: "godror",
+			script: "CREATE TABLE a (v NUMBER);\nBEGIN\n  INSERT INTO a VALUES (1);\n  COMMIT;\nEND;\n/\nSELECT * FROM a\n/\n",
+			want:   []string{"CREATE TABLE a (v NUMBER)", "BEGIN\n  INSERT INTO a VALUES (1);\n  COMMIT;\nEND;", "SELECT * FROM a"},
+		},
+		{
+			name:   "mysql delimiter",
+			dbType: "mysql",
+			script: "DELIMITER //\nCREATE PROCEDURE p() BEGIN SELECT 'a\\';'; END//\nDELIMITER ;\n# call it;\nCALL p();",
+			want:   []string{"CREATE PROCEDURE p() BEGIN SELECT 'a\\';'; END", "# call it;\nCALL p()"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, SplitStatements(tt.dbType, tt.script))
+		})
+	}
+}
+
+func TestScript(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	d := &Database{DB: map[string]*Info{"sqlite": {DB: db, DBType: "sqlite3"}}}
+
+	result, err := d.Script(t.Context(), "sqlite", `
+		CREATE TEMP TABLE items (id INTEGER, name TEXT);
+		INSERT INTO items VALUES (1, 'a'), (2, 'b'), (3, :name);
+		SELECT count(*) AS total FROM items;
+		SELECT name FROM items WHERE id > :id ORDER BY id;
+	`, map[string]any{"name": "c", "id": 1}, 0, false)
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"statement", "result_sets", "rows", "rows_affected", "duration"}, result.Columns())
+	require.Len(t, result.Rows(), 4)
+	require.EqualValues(t, 3, result.RowsAffected())
+
+	results := result.(*Result).Results()
+	require.Len(t, results, 4)
+	require.Equal(t, []string{"status"}, results[0].Columns())
+	require.EqualValues(t, 3, results[1].RowsAffected())
+	require.Equal(t, [][]any{{"3"}}, results[2].Rows())
+	require.Equal(t, []string{"name"}, results[3].Columns())
+	require.Equal(t, [][]any{{"b"}, {"c"}}, results[3].Rows())
+
+	_, err = d.Script(t.Context(), "sqlite", "SELECT 1; SELECT * FROM missing;", nil, 0, false)
+	require.ErrorContains(t, err, "statement 2")
+}
@@ -0,0 +1,345 @@
+package database
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/worldline-go/conn/database"
+	"github.com/worldline-go/saz/internal/config"
+	"github.com/worldline-go/saz/internal/service"
+)
+
+const (
+	DefaultCheckInterval = 30 * time.Second
+
+	connectTimeout = 10 * time.Second
+	pingTimeout    = 5 * time.Second
+	minBackoff     = 5 * time.Second
+	maxBackoff     = 5 * time.Minute
+)
+
+// conn returns the connected database, a database which failed to connect is tried again after its backoff.
+// Caller must release the database after its query has a connection, a replaced pool is closed after that.
+func (d *Database) conn(ctx context.Context, name string) (*Info, error) {
+	dbConn, err := d.acquire(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := dbConn.available(ctx); err != nil {
+		dbConn.release()
+
+		return nil, err
+	}
+
+	return dbConn, nil
+}
+
+// acquire returns the database of the name, a database replaced after the lookup is looked up again.
+func (d *Database) acquire(name string) (*Info, error) {
+	var closed *Info
+	for {
+		dbConn, ok := d.info(name)
+		if !ok {
+			return nil, fmt.Errorf("database %s; %w", name, service.ErrNotExists)
+		}
+
+		// closed databases stay in the map only on shutdown
+		if dbConn == closed {
+			return nil, fmt.Errorf("database %s is closed; %w", name, service.ErrUnavailable)
+		}
+
+		if dbConn.acquire() {
+			return dbConn, nil
+		}
+
+		closed = dbConn
+	}
+}
+
+// available connects the database when it is not connected and its backoff is passed.
+func (i *Info) available(ctx context.Context) error {
+	i.mu.Lock()
+	connected := i.DB != nil
+	retryAt, lastErr := i.retryAt, i.status.Error
+	i.mu.Unlock()
+
+	if connected {
+		return nil
+	}
+
+	if time.Now().Before(retryAt) {
+		return fmt.Errorf("database %s is unavailable: %s; %w", i.name, lastErr, service.ErrUnavailable)
+	}
+
+	if err := i.connect(ctx); err != nil {
+		return fmt.Errorf("database %s is unavailable: %w; %w", i.name, err, service.ErrUnavailable)
+	}
+
+	return nil
+}
+
+// conn returns the connection pool, nil when it is not connected.
+func (i *Info) conn() *sql.DB {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	return i.DB
+}
+
+// connect opens the connection pool, failures set the next retry with exponential backoff.
+// The dial runs without holding mu, concurrent calls wait for the running one.
+func (i *Info) connect(ctx context.Context) error {
+	i.mu.Lock()
+	if i.DB != nil {
+		i.mu.Unlock()
+
+		return nil
+	}
+
+	if i.closed {
+		i.mu.Unlock()
+
+		return fmt.Errorf("database %s is removed", i.name)
+	}
+
+	if connecting := i.connecting; connecting != nil {
+		i.mu.Unlock()
+
+		select {
+		case <-connecting:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		i.mu.Lock()
+		defer i.mu.Unlock()
+
+		if i.DB == nil {
+			return errors.New(cmp.Or(i.status.Error, "database is not connected"))
+		}
+
+		return nil
+	}
+
+	connecting := make(chan struct{})
+	i.connecting = connecting
+	i.mu.Unlock()
+
+	ctxConnect, cancel := context.WithTimeout(ctx, connectTimeout)
+	defer cancel()
+
+	db, err := open(ctxConnect, i.config)
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.connecting = nil
+	close(connecting)
+
+	if i.closed {
+		if db != nil {
+			_ = db.Close()
+		}
+
+		return fmt.Errorf("database %s is removed", i.name)
+	}
+
+	i.status.LastCheck = time.Now()
+	if err != nil {
+		i.backoff = min(max(i.backoff*2, minBackoff), maxBackoff)
+		i.retryAt = i.status.LastCheck.Add(i.backoff)
+		i.status.Error = err.Error()
+
+		slog.Warn("database is unavailable", "name", i.name, "type", i.DBType, "error", err, "retry_in", i.backoff.String())
+
+		return err
+	}
+
+	i.DB = db
+	i.backoff = 0
+	i.status.Error = ""
+
+	slog.Info("connected to database", "name", i.name, "type", i.DBType, "read_only", i.ReadOnly,
+		"max_open_conns", db.Stats().MaxOpenConnections)
+
+	return nil
+}
+
+// open connects to the database with the pool and session settings of the config.
+func open(ctx context.Context, cfg config.Database) (*sql.DB, error) {
+	statements, err := sessionStatements(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var db *sql.DB
+	if len(statements) == 0 {
+		db, err = sql.Open(cfg.DBType, cfg.DBDatasource)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		connector, err := newSessionConnector(cfg.DBType, cfg.DBDatasource, statements)
+		if err != nil {
+			return nil, err
+		}
+
+		db = sql.OpenDB(connector)
+	}
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+
+		return nil, err
+	}
+
+	setPool(db, cfg)
+
+	return db, nil
+}
+
+// setPool applies the pool settings of the config, unset ones are the defaults of the connection package.
+func setPool(db *sql.DB, cfg config.Database) {
+	db.SetMaxOpenConns(cmp.Or(cfg.MaxOpenConns, database.MaxOpenConns))
+	db.SetMaxIdleConns(cmp.Or(cfg.MaxIdleConns, database.MaxIdleConns))
+	db.SetConnMaxLifetime(cmp.Or(cfg.ConnMaxLifetime, database.ConnMaxLifetime))
+
+	if cfg.ConnMaxIdleTime > 0 {
+		db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+	}
+}
+
+// Watch connects unavailable databases after their backoff and pings connected ones every CheckInterval until ctx is done.
+func (d *Database) Watch(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for _, dbConn := range d.infos() {
+			// slow checks don't stack up
+			if !dbConn.checking.CompareAndSwap(false, true) {
+				continue
+			}
+
+			go func() {
+				defer dbConn.checking.Store(false)
+
+				dbConn.check(ctx, cmp.Or(d.CheckInterval, DefaultCheckInterval))
+			}()
+		}
+	}
+}
+
+// check connects the database after its backoff or pings it when the last check is older than interval.
+func (i *Info) check(ctx context.Context, interval time.Duration) {
+	i.mu.Lock()
+	db := i.DB
+	now := time.Now()
+
+	retry := !now.Before(i.retryAt)
+	due := now.Sub(i.status.LastCheck) >= interval
+	i.mu.Unlock()
+
+	if db == nil {
+		if retry {
+			_ = i.connect(ctx)
+		}
+
+		return
+	}
+
+	if !due {
+		return
+	}
+
+	ctxPing, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+
+	_ = i.ping(ctxPing, db)
+}
+
+// ping pings the connected pool and updates the status, the pool reconnects by itself.
+func (i *Info) ping(ctx context.Context, db *sql.DB) error {
+	err := db.PingContext(ctx)
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.status.LastCheck = time.Now()
+	if err != nil {
+		if i.status.Error == "" {
+			slog.Warn("database is down", "name", i.name, "error", err)
+		}
+
+		i.status.Error = err.Error()
+
+		return err
+	}
+
+	if i.status.Error != "" {
+		slog.Info("database is up again", "name", i.name)
+	}
+
+	i.status.Error = ""
+
+	return nil
+}
+
+// Ping checks the connection of the database, a database which is not connected is tried after its backoff.
+func (d *Database) Ping(ctx context.Context, name string) error {
+	dbConn, err := d.conn(ctx, name)
+	if err != nil {
+		return err
+	}
+	defer dbConn.release()
+
+	if err := dbConn.ping(ctx, dbConn.DB); err != nil {
+		return fmt.Errorf("database %s: %w; %w", name, err, service.ErrUnavailable)
+	}
+
+	return nil
+}
+
+// DatabaseList returns the databases with their availability sorted by name.
+func (d *Database) DatabaseList() []service.DatabaseStatus {
+	infos := d.infos()
+
+	list := make([]service.DatabaseStatus, 0, len(infos))
+	for name, dbConn := range infos {
+		dbConn.mu.Lock()
+		status := dbConn.status
+		connected := dbConn.DB != nil
+		dbConn.mu.Unlock()
+
+		status.Name = name
+		status.Type = dbConn.DBType
+		status.ReadOnly = dbConn.ReadOnly
+		status.Required = dbConn.config.Required
+		status.Status = service.DatabaseUp
+		if !connected || status.Error != "" {
+			status.Status = service.DatabaseDown
+		}
+
+		list = append(list, status)
+	}
+
+	slices.SortFunc(list, func(a, b service.DatabaseStatus) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return list
+}
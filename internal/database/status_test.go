@@ -0,0 +1,164 @@
+package database
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/worldline-go/saz/internal/config"
+	"github.com/worldline-go/saz/internal/service"
+)
+
+func TestConnectUnavailable(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "data")
+
+	d := Connect(t.Context(), map[string]config.Database{
+		"memory":  {DBType: "sqlite3", DBDatasource: ":memory:"},
+		"missing": {DBType: "sqlite3", DBDatasource: "file:" + filepath.Join(dir, "saz.db") + "?mode=rwc"},
+	})
+	t.Cleanup(d.Close)
+
+	list := d.DatabaseList()
+	require.Len(t, list, 2)
+	require.Equal(t, "memory", list[0].Name)
+	require.Equal(t, service.DatabaseUp, list[0].Status)
+	require.Empty(t, list[0].Error)
+	require.Equal(t, "missing", list[1].Name)
+	require.Equal(t, "sqlite3", list[1].Type)
+	require.Equal(t, service.DatabaseDown, list[1].Status)
+	require.NotEmpty(t, list[1].Error)
+	require.False(t, list[1].LastCheck.IsZero())
+
+	_, err := d.Query(t.Context(), "memory", "SELECT 1", nil, 0, false, service.Snapshot{})
+	require.NoError(t, err)
+
+	// no retry before the backoff
+	_, err = d.Query(t.Context(), "missing", "SELECT 1", nil, 0, false, service.Snapshot{})
+	require.ErrorIs(t, err, service.ErrUnavailable)
+
+	_, err = d.Query(t.Context(), "unknown", "SELECT 1", nil, 0, false, service.Snapshot{})
+	require.ErrorIs(t, err, service.ErrNotExists)
+
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+
+	d.DB["missing"].mu.Lock()
+	d.DB["missing"].retryAt = time.Now()
+	d.DB["missing"].mu.Unlock()
+
+	_, err = d.Query(t.Context(), "missing", "SELECT 1", nil, 0, false, service.Snapshot{})
+	require.NoError(t, err)
+
+	list = d.DatabaseList()
+	require.Equal(t, service.DatabaseUp, list[1].Status)
+	require.Empty(t, list[1].Error)
+}
+
+func TestConnectBackoff(t *testing.T) {
+	info := &Info{
+		DBType: "sqlite3",
+		name:   "missing",
+		config: config.Database{DBType: "sqlite3", DBDatasource: "file:" + filepath.Join(t.TempDir(), "none", "saz.db")},
+	}
+
+	var backoffs []time.Duration
+	for range 8 {
+		require.Error(t, info.connect(t.Context()))
+		backoffs = append(backoffs, info.backoff)
+	}
+
+	require.Equal(t, []time.Duration{
+		5 * time.Second, 10 * time.Second, 20 * time.Second, 40 * time.Second,
+		80 * time.Second, 160 * time.Second, 5 * time.Minute, 5 * time.Minute,
+	}, backoffs)
+}
+
+func TestPing(t *testing.T) {
+	d := Connect(t.Context(), map[string]config.Database{
+		"memory":  {DBType: "sqlite3", DBDatasource: ":memory:", Required: true},
+		"missing": {DBType: "sqlite3", DBDatasource: "file:" + filepath.Join(t.TempDir(), "none", "saz.db")},
+	})
+	t.Cleanup(d.Close)
+
+	require.NoError(t, d.Ping(t.Context(), "memory"))
+	require.ErrorIs(t, d.Ping(t.Context(), "missing"), service.ErrUnavailable)
+	require.ErrorIs(t, d.Ping(t.Context(), "unknown"), service.ErrNotExists)
+
+	list := d.DatabaseList()
+	require.True(t, list[0].Required)
+	require.False(t, list[1].Required)
+}
+
+// slowDriver blocks opening connections until release is closed.
+type slowDriver struct {
+	release chan struct{}
+	dials   atomic.Int32
+}
+
+func (d *slowDriver) Open(name string) (driver.Conn, error) {
+	d.dials.Add(1)
+	<-d.release
+
+	db, err := sql.Open("sqlite3", name)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	return db.Driver().Open(name)
+}
+
+var slowDrivers atomic.Int32
+
+func TestConnectOutsideLock(t *testing.T) {
+	drv := &slowDriver{release: make(chan struct{})}
+	// drivers can't be registered twice, test can run with -count
+	dbType := fmt.Sprintf("slow-sqlite3-%d", slowDrivers.Add(1))
+	sql.Register(dbType, drv)
+
+	d := &Database{DB: map[string]*Info{
+		"slow": newInfo("slow", config.Database{DBType: dbType, DBDatasource: ":memory:"}),
+	}}
+	t.Cleanup(d.Close)
+
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Go(func() {
+			if _, err := d.conn(t.Context(), "slow"); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+
+	// status is readable while the database is connecting
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		for drv.dials.Load() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+
+		if status := d.DatabaseList()[0].Status; status != service.DatabaseDown {
+			t.Errorf("status is %s while connecting", status)
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("database list is blocked by connect")
+	}
+
+	close(drv.release)
+	wg.Wait()
+
+	require.Equal(t, int32(1), drv.dials.Load())
+	require.Equal(t, service.DatabaseUp, d.DatabaseList()[0].Status)
+}
@@ -0,0 +1,60 @@
+package database
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/worldline-go/saz/internal/service"
+)
+
+func TestQueryStream(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	// one connection, a leaked stream blocks the next query
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec(`CREATE TABLE items (id INTEGER, name TEXT)`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO items VALUES (1, 'a'), (2, 'b'), (3, NULL)`)
+	require.NoError(t, err)
+
+	d := &Database{DB: map[string]*Info{"sqlite": {DB: db, DBType: "sqlite3"}}}
+
+	collect := func(stream *service.RowStream, stop int) [][]any {
+		var rows [][]any
+		for row, err := range stream.Rows {
+			require.NoError(t, err)
+
+			rows = append(rows, row)
+			if len(rows) == stop {
+				break
+			}
+		}
+
+		return rows
+	}
+
+	stream, err := d.QueryStream(t.Context(), "sqlite", "SELECT id, name FROM items WHERE id >= :id ORDER BY id", map[string]any{"id": 1}, 0, false, service.Snapshot{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"id", "name"}, stream.Columns)
+	require.Nil(t, stream.ColumnTypes)
+	require.Equal(t, [][]any{{"1", "a"}, {"2", "b"}, {"3", ""}}, collect(stream, 0))
+
+	stream, err = d.QueryStream(t.Context(), "sqlite", "SELECT id, name FROM items ORDER BY id", nil, 2, true, service.Snapshot{})
+	require.NoError(t, err)
+	require.Len(t, stream.ColumnTypes, 2)
+	require.Equal(t, [][]any{{int64(1), "a"}, {int64(2), "b"}}, collect(stream, 0))
+
+	// stopping early closes the rows
+	stream, err = d.QueryStream(t.Context(), "sqlite", "SELECT id FROM items ORDER BY id", nil, 0, false, service.Snapshot{})
+	require.NoError(t, err)
+	require.Len(t, collect(stream, 1), 1)
+	require.Equal(t, 0, db.Stats().InUse)
+
+	_, err = d.QueryStream(t.Context(), "sqlite", "SELECT * FROM missing", nil, 0, false, service.Snapshot{})
+	require.Error(t, err)
+	require.Equal(t, 0, db.Stats().InUse)
+}
@@ -0,0 +1,164 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/godror/godror"
+	"github.com/worldline-go/saz/internal/service"
+	"github.com/worldline-go/types"
+)
+
+var binaryTypes = map[string]struct{}{
+	"BYTEA":      {},
+	"BINARY":     {},
+	"VARBINARY":  {},
+	"RAW":        {},
+	"LONG RAW":   {},
+	"IMAGE":      {},
+	"BLOB":       {},
+	"TINYBLOB":   {},
+	"MEDIUMBLOB": {},
+	"LONGBLOB":   {},
+	"BFILE":      {},
+}
+
+var integerTypes = map[string]struct{}{
+	"INT":         {},
+	"INTEGER":     {},
+	"BIGINT":      {},
+	"SMALLINT":    {},
+	"TINYINT":     {},
+	"MEDIUMINT":   {},
+	"INT2":        {},
+	"INT4":        {},
+	"INT8":        {},
+	"SERIAL":      {},
+	"BIGSERIAL":   {},
+	"SMALLSERIAL": {},
+}
+
+// ColumnInfos returns the column metadata of typed results.
+func ColumnInfos(columnTypes []*sql.ColumnType) []service.ColumnInfo {
+	infos := make([]service.ColumnInfo, 0, len(columnTypes))
+	for _, col := range columnTypes {
+		info := service.ColumnInfo{
+			Name:         col.Name(),
+			Type:         columnKind(col.DatabaseTypeName()),
+			DatabaseType: col.DatabaseTypeName(),
+		}
+
+		if nullable, ok := col.Nullable(); ok {
+			info.Nullable = types.NewNull(nullable)
+		}
+
+		infos = append(infos, info)
+	}
+
+	return infos
+}
+
+// columnKind returns the kind of values of a database type name.
+func columnKind(name string) string {
+	name = strings.TrimPrefix(strings.ToUpper(name), "UNSIGNED ")
+	if _, ok := binaryTypes[name]; ok {
+		return service.KindBytes
+	}
+
+	if _, ok := integerTypes[name]; ok {
+		return service.KindInteger
+	}
+
+	switch {
+	case name == "", strings.HasPrefix(name, "INTERVAL"):
+		return service.KindString
+	case strings.Contains(name, "BOOL"), name == "BIT":
+		return service.KindBoolean
+	case strings.Contains(name, "FLOAT"), strings.Contains(name, "DOUBLE"), name == "REAL":
+		return service.KindNumber
+	case strings.Contains(name, "NUMERIC"), strings.Contains(name, "DECIMAL"), name == "NUMBER", strings.Contains(name, "MONEY"):
+		return service.KindDecimal
+	case strings.Contains(name, "DATE"), strings.Contains(name, "TIME"):
+		return service.KindDate
+	case strings.HasPrefix(name, "JSON"):
+		return service.KindJSON
+	case name == "UUID", name == "UNIQUEIDENTIFIER":
+		return service.KindUUID
+	}
+
+	return service.KindString
+}
+
+// typedValue converts a scanned value to a JSON friendly value of the column kind.
+// Decimals are strings to keep precision, bytes are encoded as base64 by JSON.
+func typedValue(v any, col service.ColumnInfo) any {
+	switch val := v.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return typedBytes(val, col)
+	case string:
+		if col.Type == service.KindJSON && json.Valid([]byte(val)) {
+			return json.RawMessage(val)
+		}
+
+		return val
+	case float64:
+		if math.IsNaN(val) || math.IsInf(val, 0) {
+			return strconv.FormatFloat(val, 'g', -1, 64)
+		}
+
+		return val
+	case float32:
+		return typedValue(float64(val), col)
+	case godror.Number:
+		return string(val)
+	case json.Marshaler:
+		return val
+	case fmt.Stringer:
+		return val.String()
+	}
+
+	return v
+}
+
+// typedBytes handles drivers returning text as bytes, like MySQL.
+func typedBytes(v []byte, col service.ColumnInfo) any {
+	switch col.Type {
+	case service.KindBytes:
+		return v
+	case service.KindInteger:
+		if i, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+			return i
+		}
+	case service.KindNumber:
+		if f, err := strconv.ParseFloat(string(v), 64); err == nil && !math.IsNaN(f) && !math.IsInf(f, 0) {
+			return f
+		}
+	case service.KindBoolean:
+		if len(v) == 1 && v[0] <= 1 {
+			// BIT(1) of MySQL
+			return v[0] == 1
+		}
+
+		if b, err := strconv.ParseBool(string(v)); err == nil {
+			return b
+		}
+	case service.KindJSON:
+		if json.Valid(v) {
+			return json.RawMessage(v)
+		}
+	case service.KindUUID:
+		if len(v) == 16 {
+			if id, err := uuidFromBytes(v, strings.EqualFold(col.DatabaseType, "UNIQUEIDENTIFIER")); err == nil {
+				return id.String()
+			}
+		}
+	}
+
+	return string(v)
+}
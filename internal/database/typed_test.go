@@ -0,0 +1,119 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/godror/godror"
+	"github.com/stretchr/testify/require"
+	"github.com/worldline-go/saz/internal/service"
+	"github.com/worldline-go/types"
+)
+
+func TestQueryTyped(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	_, err = db.Exec(`CREATE TABLE events (
+		id INTEGER, amount REAL, active BOOLEAN, name TEXT, payload BLOB, created DATETIME
+	)`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO events VALUES
+		(1, 1.5, true, 'a', x'0102', '2024-03-01 10:30:00'),
+		(2, NULL, NULL, NULL, NULL, NULL)`)
+	require.NoError(t, err)
+
+	d := &Database{DB: map[string]*Info{"sqlite": {DB: db, DBType: "sqlite3"}}}
+
+	result, err := d.Query(t.Context(), "sqlite", "SELECT * FROM events ORDER BY id", nil, 0, true, service.Snapshot{})
+	require.NoError(t, err)
+
+	require.Equal(t, []service.ColumnInfo{
+		{Name: "id", Type: service.KindInteger, DatabaseType: "INTEGER", Nullable: types.NewNull(true)},
+		{Name: "amount", Type: service.KindNumber, DatabaseType: "REAL", Nullable: types.NewNull(true)},
+		{Name: "active", Type: service.KindBoolean, DatabaseType: "BOOLEAN", Nullable: types.NewNull(true)},
+		{Name: "name", Type: service.KindString, DatabaseType: "TEXT", Nullable: types.NewNull(true)},
+		{Name: "payload", Type: service.KindBytes, DatabaseType: "BLOB", Nullable: types.NewNull(true)},
+		{Name: "created", Type: service.KindDate, DatabaseType: "DATETIME", Nullable: types.NewNull(true)},
+	}, result.ColumnTypes())
+
+	rows, err := json.Marshal(result.Rows())
+	require.NoError(t, err)
+	require.JSONEq(t, `[
+		[1, 1.5, true, "a", "AQI=", "2024-03-01T10:30:00Z"],
+		[2, null, null, null, null, null]
+	]`, string(rows))
+
+	// default result keeps string values
+	result, err = d.Query(t.Context(), "sqlite", "SELECT * FROM events ORDER BY id", nil, 0, false, service.Snapshot{})
+	require.NoError(t, err)
+	require.Nil(t, result.ColumnTypes())
+	require.Equal(t, []any{"2", "", "", "", "", ""}, result.Rows()[1])
+}
+
+func TestTypedValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+		col   service.ColumnInfo
+		want  any
+	}{
+		{
+			name:  "mysql int as bytes",
+			value: []byte("42"),
+			col:   service.ColumnInfo{Type: columnKind("UNSIGNED BIGINT")},
+			want:  int64(42),
+		},
+		{
+			name:  "mysql decimal as bytes",
+			value: []byte("12.50"),
+			col:   service.ColumnInfo{Type: columnKind("DECIMAL")},
+			want:  "12.50",
+		},
+		{
+			name:  "mysql bit",
+			value: []byte{1},
+			col:   service.ColumnInfo{Type: columnKind("BIT")},
+			want:  true,
+		},
+		{
+			name:  "oracle number",
+			value: godror.Number("3.14"),
+			col:   service.ColumnInfo{Type: columnKind("NUMBER")},
+			want:  "3.14",
+		},
+		{
+			name:  "jsonb",
+			value: []byte(`{"a":1}`),
+			col:   service.ColumnInfo{Type: columnKind("JSONB")},
+			want:  json.RawMessage(`{"a":1}`),
+		},
+		{
+			name:  "mssql uniqueidentifier",
+			value: []byte{0xff, 0x19, 0x96, 0x6f, 0x86, 0x8b, 0x11, 0xd0, 0xb4, 0x2d, 0x00, 0xc0, 0x4f, 0xc9, 0x64, 0xff},
+			col:   service.ColumnInfo{Type: columnKind("UNIQUEIDENTIFIER"), DatabaseType: "UNIQUEIDENTIFIER"},
+			want:  "6f9619ff-8b86-d011-b42d-00c04fc964ff",
+		},
+		{
+			name:  "nan",
+			value: math.NaN(),
+			col:   service.ColumnInfo{Type: columnKind("FLOAT8")},
+			want:  "NaN",
+		},
+		{
+			name:  "postgres point is not integer",
+			value: "(1,2)",
+			col:   service.ColumnInfo{Type: columnKind("POINT")},
+			want:  "(1,2)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, typedValue(tt.value, tt.col))
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package export
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/spf13/cast"
+	"github.com/worldline-go/saz/internal/service"
+)
+
+const (
+	FormatJSON    = "json"
+	FormatNDJSON  = "ndjson"
+	FormatCSV     = "csv"
+	FormatTSV     = "tsv"
+	FormatXLSX    = "xlsx"
+	FormatParquet = "parquet"
+)
+
+// Writer writes a query result in a format while rows are given.
+type Writer interface {
+	Header(columns []string, columnTypes []service.ColumnInfo) error
+	Row(row []any) error
+	// End finishes the output, err is the error of reading rows after the header.
+	End(err error) error
+}
+
+type format struct {
+	contentType string
+	newWriter   func(w io.Writer) Writer
+}
+
+var formats = map[string]format{
+	FormatJSON:    {contentType: "application/json", newWriter: newJSONWriter},
+	FormatNDJSON:  {contentType: "application/x-ndjson", newWriter: newNDJSONWriter},
+	FormatCSV:     {contentType: "text/csv; charset=utf-8", newWriter: newCSVWriter(',')},
+	FormatTSV:     {contentType: "text/tab-separated-values; charset=utf-8", newWriter: newCSVWriter('\t')},
+	FormatXLSX:    {contentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", newWriter: newXLSXWriter},
+	FormatParquet: {contentType: "application/vnd.apache.parquet", newWriter: newParquetWriter},
+}
+
+// Check returns the lower case format name, error for unsupported formats.
+func Check(name string) (string, error) {
+	name = strings.ToLower(name)
+	if _, ok := formats[name]; !ok {
+		return "", fmt.Errorf("unsupported format %s; %w", name, service.ErrBadRequest)
+	}
+
+	return name, nil
+}
+
+// New returns a writer of the format writing to w.
+func New(name string, w io.Writer) (Writer, error) {
+	f, ok := formats[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported format %s; %w", name, service.ErrBadRequest)
+	}
+
+	return f.newWriter(w), nil
+}
+
+// ContentType returns the media type of the format.
+func ContentType(name string) string {
+	return formats[name].contentType
+}
+
+// FromAccept returns the format of an Accept header, empty when no format matches.
+func FromAccept(accept string) string {
+	// JSON is the default response
+	for _, name := range []string{FormatNDJSON, FormatCSV, FormatTSV, FormatXLSX, FormatParquet} {
+		mediaType, _, _ := strings.Cut(formats[name].contentType, ";")
+		if strings.Contains(accept, mediaType) {
+			return name
+		}
+	}
+
+	return ""
+}
+
+// Write writes the columns and rows of the result.
+func Write(w io.Writer, name string, result service.Result) error {
+	writer, err := New(name, w)
+	if err != nil {
+		return err
+	}
+
+	if err := writer.Header(result.Columns(), result.ColumnTypes()); err != nil {
+		return err
+	}
+
+	for _, row := range result.Rows() {
+		if err := writer.Row(row); err != nil {
+			return err
+		}
+	}
+
+	return writer.End(nil)
+}
+
+// textValue formats a result value for text outputs like CSV, NULL is empty.
+func textValue(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case json.RawMessage:
+		return string(val)
+	case []byte:
+		return base64.StdEncoding.EncodeToString(val)
+	case time.Time:
+		return val.Format(time.RFC3339Nano)
+	}
+
+	return cast.ToString(v)
+}
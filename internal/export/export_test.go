@@ -0,0 +1,182 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/require"
+	"github.com/worldline-go/saz/internal/service"
+	"github.com/xuri/excelize/v2"
+)
+
+type testResult struct {
+	columns     []string
+	columnTypes []service.ColumnInfo
+	rows        [][]any
+}
+
+func (r *testResult) Columns() []string                 { return r.columns }
+func (r *testResult) ColumnTypes() []service.ColumnInfo { return r.columnTypes }
+func (r *testResult) Rows() [][]any                     { return r.rows }
+func (r *testResult) RowsAffected() int64               { return 0 }
+func (r *testResult) Duration() time.Duration           { return 0 }
+
+func typedResult() *testResult {
+	return &testResult{
+		columns: []string{"name", "id", "price", "active", "created", "payload", "doc"},
+		columnTypes: []service.ColumnInfo{
+			{Name: "name", Type: "string"},
+			{Name: "id", Type: "integer"},
+			{Name: "price", Type: "decimal"},
+			{Name: "active", Type: "boolean"},
+			{Name: "created", Type: "date"},
+			{Name: "payload", Type: "bytes"},
+			{Name: "doc", Type: "json"},
+		},
+		rows: [][]any{
+			{"a, \"b\"", int64(1), "12.50", true, time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC), []byte("hi"), json.RawMessage(`{"k":1}`)},
+			{nil, int64(2), nil, false, nil, nil, nil},
+		},
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{
+			format: FormatCSV,
+			want: "name,id,price,active,created,payload,doc\n" +
+				"\"a, \"\"b\"\"\",1,12.50,true,2024-03-01T10:30:00Z,aGk=,\"{\"\"k\"\":1}\"\n" +
+				",2,,false,,,\n",
+		},
+		{
+			format: FormatTSV,
+			want: "name\tid\tprice\tactive\tcreated\tpayload\tdoc\n" +
+				"\"a, \"\"b\"\"\"\t1\t12.50\ttrue\t2024-03-01T10:30:00Z\taGk=\t\"{\"\"k\"\":1}\"\n" +
+				"\t2\t\tfalse\t\t\t\n",
+		},
+		{
+			format: FormatNDJSON,
+			want: `{"columns":["name","id","price","active","created","payload","doc"],"column_types":[{"name":"name","type":"string","database_type":""},{"name":"id","type":"integer","database_type":""},{"name":"price","type":"decimal","database_type":""},{"name":"active","type":"boolean","database_type":""},{"name":"created","type":"date","database_type":""},{"name":"payload","type":"bytes","database_type":""},{"name":"doc","type":"json","database_type":""}]}` + "\n" +
+				`["a, \"b\"",1,"12.50",true,"2024-03-01T10:30:00Z","aGk=",{"k":1}]` + "\n" +
+				`[null,2,null,false,null,null,null]` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, Write(&buf, tt.format, typedResult()))
+			require.Equal(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, FormatJSON, &testResult{columns: []string{"a"}, rows: [][]any{{"1"}, {"2"}}}))
+	require.JSONEq(t, `{"columns":["a"],"rows":[["1"],["2"]]}`, buf.String())
+
+	// error after rows are started closes the document
+	buf.Reset()
+	w, err := New(FormatJSON, &buf)
+	require.NoError(t, err)
+	require.NoError(t, w.Header(nil, nil))
+	require.NoError(t, w.Row([]any{"1"}))
+	require.NoError(t, w.End(errors.New("connection lost")))
+	require.JSONEq(t, `{"rows":[["1"]],"error":"connection lost"}`, buf.String())
+}
+
+func TestWriteXLSX(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, FormatXLSX, typedResult()))
+
+	f, err := excelize.OpenReader(&buf)
+	require.NoError(t, err)
+	t.Cleanup(func() { f.Close() })
+
+	rows, err := f.GetRows(xlsxSheet)
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+	require.Equal(t, []string{"name", "id", "price", "active", "created", "payload", "doc"}, rows[0])
+	require.Equal(t, []string{"a, \"b\"", "1", "12.50", "TRUE"}, rows[1][:4])
+	require.Equal(t, []string{"aGk=", `{"k":1}`}, rows[1][5:])
+
+	cellType, err := f.GetCellType(xlsxSheet, "B2")
+	require.NoError(t, err)
+	require.NotEqual(t, excelize.CellTypeSharedString, cellType)
+}
+
+func TestWriteParquet(t *testing.T) {
+	var buf bytes.Buffer
+	result := typedResult()
+	// duplicate names get a suffix
+	result.columns = append(result.columns, "id")
+	result.columnTypes = append(result.columnTypes, service.ColumnInfo{Name: "id", Type: "number"})
+	result.rows[0] = append(result.rows[0], 1.5)
+	result.rows[1] = append(result.rows[1], nil)
+
+	require.NoError(t, Write(&buf, FormatParquet, result))
+
+	type record struct {
+		Name    *string  `parquet:"name,optional"`
+		ID      *int64   `parquet:"id,optional"`
+		ID2     *float64 `parquet:"id_2,optional"`
+		Price   *string  `parquet:"price,optional"`
+		Active  *bool    `parquet:"active,optional"`
+		Created *int64   `parquet:"created,optional"`
+		Payload []byte   `parquet:"payload,optional"`
+		Doc     *string  `parquet:"doc,optional"`
+	}
+
+	records, err := parquet.Read[record](bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+
+	first := records[0]
+	require.Equal(t, "a, \"b\"", *first.Name)
+	require.EqualValues(t, 1, *first.ID)
+	require.InDelta(t, 1.5, *first.ID2, 0)
+	require.Equal(t, "12.50", *first.Price)
+	require.True(t, *first.Active)
+	require.Equal(t, time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC).UnixMicro(), *first.Created)
+	require.Equal(t, []byte("hi"), first.Payload)
+	require.Equal(t, `{"k":1}`, *first.Doc)
+
+	second := records[1]
+	require.Nil(t, second.Name)
+	require.EqualValues(t, 2, *second.ID)
+	require.Nil(t, second.ID2)
+	require.Nil(t, second.Created)
+	require.False(t, *second.Active)
+}
+
+func TestParquetNames(t *testing.T) {
+	for _, tt := range []struct {
+		columns []string
+		want    []string
+	}{
+		{[]string{"id", "name"}, []string{"id", "name"}},
+		{[]string{"id", "id", "id"}, []string{"id", "id_2", "id_3"}},
+		{[]string{"id", "id", "id_2"}, []string{"id", "id_3", "id_2"}},
+		{[]string{"id_2", "id", "id"}, []string{"id_2", "id", "id_3"}},
+		{[]string{"id", "id_2", "id_2", "id"}, []string{"id", "id_2", "id_2_2", "id_3"}},
+	} {
+		names := parquetNames(tt.columns)
+		require.Equal(t, tt.want, names, tt.columns)
+	}
+}
+
+func TestFromAccept(t *testing.T) {
+	require.Equal(t, FormatCSV, FromAccept("text/csv"))
+	require.Equal(t, FormatNDJSON, FromAccept("application/x-ndjson, */*"))
+	require.Equal(t, FormatParquet, FromAccept("application/vnd.apache.parquet"))
+	require.Empty(t, FromAccept("application/json"))
+	require.Empty(t, FromAccept("*/*"))
+}
@@ -0,0 +1,177 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/spf13/cast"
+	"github.com/worldline-go/saz/internal/service"
+)
+
+// parquetWriter writes optional columns typed by the column types, untyped results are strings.
+type parquetWriter struct {
+	w      io.Writer
+	writer *parquet.Writer
+	kinds  []string
+	// leaves is the parquet column index of every result column, parquet orders columns by name.
+	leaves []int
+	row    parquet.Row
+}
+
+func newParquetWriter(w io.Writer) Writer {
+	return &parquetWriter{w: w}
+}
+
+func (w *parquetWriter) Header(columns []string, columnTypes []service.ColumnInfo) error {
+	names := parquetNames(columns)
+	group := make(parquet.Group, len(columns))
+	w.kinds = make([]string, len(columns))
+
+	for i, name := range names {
+		kind := service.KindString
+		if i < len(columnTypes) {
+			kind = columnTypes[i].Type
+		}
+
+		w.kinds[i] = kind
+		group[name] = parquet.Optional(parquetNode(kind))
+	}
+
+	sorted := slices.Clone(names)
+	slices.Sort(sorted)
+
+	w.leaves = make([]int, len(names))
+	for i, name := range names {
+		w.leaves[i], _ = slices.BinarySearch(sorted, name)
+	}
+
+	w.writer = parquet.NewWriter(w.w, parquet.NewSchema("result", group))
+	w.row = make(parquet.Row, len(columns))
+
+	return nil
+}
+
+// parquetNames returns unique names of the columns, parquet needs unique names.
+// First column of a name keeps it, duplicates get the first free _N suffix which is not a name of another column.
+func parquetNames(columns []string) []string {
+	used := make(map[string]struct{}, len(columns))
+	for _, column := range columns {
+		used[column] = struct{}{}
+	}
+
+	names := make([]string, len(columns))
+	seen := make(map[string]struct{}, len(columns))
+	for i, column := range columns {
+		if _, ok := seen[column]; !ok {
+			seen[column] = struct{}{}
+			names[i] = column
+
+			continue
+		}
+
+		name := column
+		for n := 2; ; n++ {
+			name = column + "_" + strconv.Itoa(n)
+			if _, ok := used[name]; !ok {
+				break
+			}
+		}
+
+		used[name] = struct{}{}
+		names[i] = name
+	}
+
+	return names
+}
+
+func parquetNode(kind string) parquet.Node {
+	switch kind {
+	case service.KindInteger:
+		return parquet.Int(64)
+	case service.KindNumber:
+		return parquet.Leaf(parquet.DoubleType)
+	case service.KindBoolean:
+		return parquet.Leaf(parquet.BooleanType)
+	case service.KindDate:
+		return parquet.Timestamp(parquet.Microsecond)
+	case service.KindBytes:
+		return parquet.Leaf(parquet.ByteArrayType)
+	default:
+		return parquet.String()
+	}
+}
+
+func (w *parquetWriter) Row(row []any) error {
+	for i, v := range row {
+		leaf := w.leaves[i]
+		if v == nil {
+			w.row[leaf] = parquet.NullValue().Level(0, 0, leaf)
+
+			continue
+		}
+
+		value, err := parquetValue(w.kinds[i], v)
+		if err != nil {
+			return fmt.Errorf("column %d: %w", i+1, err)
+		}
+
+		w.row[leaf] = value.Level(0, 1, leaf)
+	}
+
+	if _, err := w.writer.WriteRows([]parquet.Row{w.row}); err != nil {
+		return fmt.Errorf("write row: %w", err)
+	}
+
+	return nil
+}
+
+func parquetValue(kind string, v any) (parquet.Value, error) {
+	switch kind {
+	case service.KindInteger:
+		i, err := cast.ToInt64E(v)
+		if err != nil {
+			return parquet.Value{}, err
+		}
+
+		return parquet.Int64Value(i), nil
+	case service.KindNumber:
+		f, err := cast.ToFloat64E(v)
+		if err != nil {
+			return parquet.Value{}, err
+		}
+
+		return parquet.DoubleValue(f), nil
+	case service.KindBoolean:
+		b, err := cast.ToBoolE(v)
+		if err != nil {
+			return parquet.Value{}, err
+		}
+
+		return parquet.BooleanValue(b), nil
+	case service.KindDate:
+		t, err := cast.ToTimeE(v)
+		if err != nil {
+			return parquet.Value{}, err
+		}
+
+		return parquet.Int64Value(t.UTC().Truncate(time.Microsecond).UnixMicro()), nil
+	case service.KindBytes:
+		if b, ok := v.([]byte); ok {
+			return parquet.ByteArrayValue(b), nil
+		}
+	}
+
+	return parquet.ByteArrayValue([]byte(textValue(v))), nil
+}
+
+func (w *parquetWriter) End(_ error) error {
+	if w.writer == nil {
+		return nil
+	}
+
+	return w.writer.Close()
+}
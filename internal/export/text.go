@@ -0,0 +1,146 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/worldline-go/saz/internal/service"
+)
+
+type header struct {
+	Columns     []string             `json:"columns,omitempty"`
+	ColumnTypes []service.ColumnInfo `json:"column_types,omitempty"`
+}
+
+// ndjsonWriter writes the columns as the first object line, then every row as an array line.
+type ndjsonWriter struct {
+	enc *json.Encoder
+}
+
+func newNDJSONWriter(w io.Writer) Writer {
+	return &ndjsonWriter{enc: json.NewEncoder(w)}
+}
+
+func (w *ndjsonWriter) Header(columns []string, columnTypes []service.ColumnInfo) error {
+	return w.enc.Encode(header{Columns: columns, ColumnTypes: columnTypes})
+}
+
+func (w *ndjsonWriter) Row(row []any) error {
+	return w.enc.Encode(row)
+}
+
+func (w *ndjsonWriter) End(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return w.enc.Encode(struct {
+		Error string `json:"error"`
+	}{Error: err.Error()})
+}
+
+// jsonWriter writes one JSON document like the query response with rows written while given.
+type jsonWriter struct {
+	w     io.Writer
+	count int
+}
+
+func newJSONWriter(w io.Writer) Writer {
+	return &jsonWriter{w: w}
+}
+
+func (w *jsonWriter) Header(columns []string, columnTypes []service.ColumnInfo) error {
+	v, err := json.Marshal(header{Columns: columns, ColumnTypes: columnTypes})
+	if err != nil {
+		return err
+	}
+
+	// open the rows array in place of the closing brace
+	if _, err := w.w.Write(v[:len(v)-1]); err != nil {
+		return err
+	}
+
+	if len(v) > 2 {
+		_, err = io.WriteString(w.w, `,"rows":[`)
+	} else {
+		_, err = io.WriteString(w.w, `"rows":[`)
+	}
+
+	return err
+}
+
+func (w *jsonWriter) Row(row []any) error {
+	v, err := json.Marshal(row)
+	if err != nil {
+		return err
+	}
+
+	if w.count > 0 {
+		if _, err := io.WriteString(w.w, ",\n"); err != nil {
+			return err
+		}
+	}
+
+	w.count++
+
+	_, err = w.w.Write(v)
+
+	return err
+}
+
+func (w *jsonWriter) End(err error) error {
+	if err == nil {
+		_, err := io.WriteString(w.w, "]}\n")
+
+		return err
+	}
+
+	errValue, _ := json.Marshal(err.Error())
+	_, errWrite := fmt.Fprintf(w.w, `],"error":%s}`+"\n", errValue)
+
+	return errWrite
+}
+
+// csvWriter writes a header line with columns then the rows, NULL is empty and bytes are base64.
+type csvWriter struct {
+	w      *csv.Writer
+	record []string
+}
+
+func newCSVWriter(comma rune) func(w io.Writer) Writer {
+	return func(w io.Writer) Writer {
+		cw := csv.NewWriter(w)
+		cw.Comma = comma
+
+		return &csvWriter{w: cw}
+	}
+}
+
+func (w *csvWriter) Header(columns []string, _ []service.ColumnInfo) error {
+	w.record = make([]string, len(columns))
+
+	return w.w.Write(columns)
+}
+
+func (w *csvWriter) Row(row []any) error {
+	for i, v := range row {
+		w.record[i] = textValue(v)
+	}
+
+	if err := w.w.Write(w.record); err != nil {
+		return err
+	}
+
+	// write to the output instead of keeping the whole buffer
+	w.w.Flush()
+
+	return w.w.Error()
+}
+
+func (w *csvWriter) End(_ error) error {
+	w.w.Flush()
+
+	return w.w.Error()
+}
@@ -0,0 +1,88 @@
+package export
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/worldline-go/saz/internal/service"
+	"github.com/xuri/excelize/v2"
+)
+
+const xlsxSheet = "Sheet1"
+
+// xlsxWriter writes rows to a sheet stream, the file is written to the output at the end.
+type xlsxWriter struct {
+	w      io.Writer
+	file   *excelize.File
+	stream *excelize.StreamWriter
+	row    int
+	values []any
+}
+
+func newXLSXWriter(w io.Writer) Writer {
+	return &xlsxWriter{w: w}
+}
+
+func (w *xlsxWriter) Header(columns []string, _ []service.ColumnInfo) error {
+	w.file = excelize.NewFile()
+
+	stream, err := w.file.NewStreamWriter(xlsxSheet)
+	if err != nil {
+		return fmt.Errorf("create sheet: %w", err)
+	}
+
+	w.stream = stream
+	w.values = make([]any, len(columns))
+
+	for i, column := range columns {
+		w.values[i] = column
+	}
+
+	return w.setRow()
+}
+
+func (w *xlsxWriter) Row(row []any) error {
+	for i, v := range row {
+		switch val := v.(type) {
+		case json.RawMessage:
+			w.values[i] = string(val)
+		case []byte:
+			w.values[i] = base64.StdEncoding.EncodeToString(val)
+		default:
+			w.values[i] = val
+		}
+	}
+
+	return w.setRow()
+}
+
+func (w *xlsxWriter) setRow() error {
+	w.row++
+
+	cell, err := excelize.CoordinatesToCellName(1, w.row)
+	if err != nil {
+		return err
+	}
+
+	if err := w.stream.SetRow(cell, w.values); err != nil {
+		return fmt.Errorf("write row %d: %w", w.row, err)
+	}
+
+	return nil
+}
+
+func (w *xlsxWriter) End(_ error) error {
+	if w.file == nil {
+		return nil
+	}
+
+	defer w.file.Close()
+
+	if err := w.stream.Flush(); err != nil {
+		return fmt.Errorf("flush sheet: %w", err)
+	}
+
+	return w.file.Write(w.w)
+}
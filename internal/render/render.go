@@ -1,11 +1,58 @@
 package render
 
 import (
+	"bytes"
+	"log/slog"
+	"sync"
+	"text/template"
+
+	"github.com/rytsh/mugo/fstore"
 	_ "github.com/rytsh/mugo/fstore/registry"
 	"github.com/rytsh/mugo/render"
 )
 
-var (
-	ExecuteWithData = render.ExecuteWithData
-	Execute         = render.Execute
-)
+// options are shared by ExecuteWithData and Parse so both see the same functions.
+func options() []fstore.OptionFunc {
+	return []fstore.OptionFunc{
+		fstore.WithLog(slog.Default()),
+		fstore.WithTrust(true),
+	}
+}
+
+var renderer = sync.OnceValue(func() *render.Render {
+	return render.NewRender(options()...)
+})
+
+func ExecuteWithData(content string, data any) ([]byte, error) {
+	return renderer().ExecuteWithData(content, data)
+}
+
+func Execute(content string) ([]byte, error) {
+	return renderer().Execute(content)
+}
+
+// Template is parsed once and safe to execute concurrently with different data.
+type Template struct {
+	tpl *template.Template
+}
+
+func Parse(content string) (*Template, error) {
+	tpl := template.New("")
+	funcs := fstore.FuncMap(append(options(), fstore.WithExecuteTemplate(tpl))...)
+
+	tpl, err := tpl.Funcs(funcs).Parse(content)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Template{tpl: tpl}, nil
+}
+
+func (t *Template) ExecuteWithData(data any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := t.tpl.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,96 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+
+	"github.com/rakunlabs/ada"
+	"github.com/worldline-go/saz/internal/service"
+)
+
+// admin allows the request only with the Admin-Token header of the config, without admin token they are forbidden.
+func (s *Server) admin(next func(c *ada.Context) error) func(c *ada.Context) error {
+	return func(c *ada.Context) error {
+		token := c.Request.Header.Get("Admin-Token")
+		if s.config.AdminToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.config.AdminToken)) != 1 {
+			return c.SetStatus(http.StatusForbidden).SendJSON(Response{
+				Message: "Admin token is required",
+			})
+		}
+
+		return next(c)
+	}
+}
+
+func (s *Server) getConnections(c *ada.Context) error {
+	conns, err := s.service.GetConnections(c.Request.Context())
+	if err != nil {
+		return c.SetStatus(http.StatusInternalServerError).SendJSON(Response{
+			Message: "Failed to retrieve databases",
+			Error:   err.Error(),
+		})
+	}
+
+	return c.SetStatus(http.StatusOK).SendJSON(Response{
+		Data: conns,
+	})
+}
+
+func (s *Server) putConnection(c *ada.Context) error {
+	conn, err := requestConnection(c.Request)
+	if err != nil {
+		return c.SetStatus(http.StatusBadRequest).SendJSON(Response{
+			Message: "Invalid database format",
+			Error:   err.Error(),
+		})
+	}
+
+	if err := s.service.SetConnection(c.Request.Context(), conn); err != nil {
+		return sendRunError(c, err, "Invalid database")
+	}
+
+	return c.SetStatus(http.StatusOK).SendJSON(Response{
+		Message: "Database saved successfully",
+	})
+}
+
+func (s *Server) testConnection(c *ada.Context) error {
+	conn, err := requestConnection(c.Request)
+	if err != nil {
+		return c.SetStatus(http.StatusBadRequest).SendJSON(Response{
+			Message: "Invalid database format",
+			Error:   err.Error(),
+		})
+	}
+
+	if err := s.service.TestConnection(c.Request.Context(), conn); err != nil {
+		return sendRunError(c, err, "Invalid database")
+	}
+
+	return c.SetStatus(http.StatusOK).SendJSON(Response{
+		Message: "Database connected successfully",
+	})
+}
+
+func (s *Server) deleteConnection(c *ada.Context) error {
+	if err := s.service.DeleteConnection(c.Request.Context(), c.Request.PathValue("name")); err != nil {
+		return sendRunError(c, err, "Invalid database")
+	}
+
+	return c.SetStatus(http.StatusOK).SendJSON(Response{
+		Message: "Database deleted successfully",
+	})
+}
+
+// requestConnection decodes the connection of the body with the name of the path.
+func requestConnection(r *http.Request) (*service.Connection, error) {
+	var conn service.Connection
+	if err := json.NewDecoder(r.Body).Decode(&conn); err != nil {
+		return nil, err
+	}
+
+	conn.Name = r.PathValue("name")
+
+	return &conn, nil
+}
@@ -23,6 +23,11 @@ func getValuesFromRequest(r *http.Request) (map[string]any, error) {
 	}
 
 	for k, v := range r.URL.Query() {
+		if _, ok := formatParam(k, v[0]); ok {
+			// response format, not cell data
+			continue
+		}
+
 		values[k] = v[0]
 	}
 
@@ -23,6 +23,8 @@ type CellWithValues struct {
 func (s *Server) run(c *ada.Context) error {
 	ctx := context.WithoutCancel(c.Request.Context())
 
+	format, stream := responseFormat(c.Request)
+
 	var cell CellWithValues
 	if err := json.NewDecoder(c.Request.Body).Decode(&cell); err != nil {
 		return c.SetStatus(http.StatusBadRequest).SendJSON(Response{
@@ -47,33 +49,99 @@ func (s *Server) run(c *ada.Context) error {
 
 	cell.Values["cells"] = cellResult
 
-	result, err := s.service.Run(ctx, &cell.Cell, cell.Values, nil)
-	if err != nil {
-		if errors.Is(err, service.ErrNotExists) {
-			return c.SetStatus(http.StatusNotFound).SendJSON(Response{
-				Message: "Resource not found",
-				Error:   err.Error(),
-			})
+	if stream {
+		// canceled when the client disconnects
+		rowStream, err := s.service.RunStream(c.Request.Context(), &cell.Cell, cell.Values)
+		if err != nil {
+			return sendRunError(c, err, "Invalid cell data")
 		}
 
-		if errors.Is(err, service.ErrBadRequest) {
-			return c.SetStatus(http.StatusBadRequest).SendJSON(Response{
-				Message: "Invalid cell data",
-				Error:   err.Error(),
-			})
-		}
+		return sendStream(c, format, rowStream)
+	}
 
-		return c.SetStatus(http.StatusInternalServerError).SendJSON(Response{
-			Message: "Failed to execute query",
-			Error:   err.Error(),
-		})
+	result, err := s.service.Run(ctx, &cell.Cell, cell.Values, nil)
+	if err != nil {
+		return sendRunError(c, err, "Invalid cell data")
 	}
 
-	return c.SetStatus(http.StatusOK).SendJSON(ResponseQuery{
+	if format != "" {
+		return sendFile(c, format, "result", result)
+	}
+
+	return c.SetStatus(http.StatusOK).SendJSON(responseQuery(result))
+}
+
+func responseQuery(result service.Result) ResponseQuery {
+	response := ResponseQuery{
 		RowsAffected: result.RowsAffected(),
 		Columns:      result.Columns(),
+		ColumnTypes:  result.ColumnTypes(),
 		Rows:         result.Rows(),
 		Duration:     result.Duration().Truncate(time.Microsecond).String(),
+	}
+
+	if pagedResult, ok := result.(service.PagedResult); ok {
+		response.NextPage = pagedResult.NextPage()
+	}
+
+	if planResult, ok := result.(service.PlanResult); ok {
+		response.Plan = planResult.Plan()
+	}
+
+	if cachedResult, ok := result.(service.CachedResult); ok {
+		info := cachedResult.Cache()
+		response.Cache = &info
+	}
+
+	if multiResult, ok := result.(service.MultiResult); ok {
+		for _, r := range multiResult.Results() {
+			response.Results = append(response.Results, responseQuery(r))
+		}
+	}
+
+	return response
+}
+
+// sendRunError sends the error of a cell run with the status of its kind.
+func sendRunError(c *ada.Context, err error, badRequestMessage string) error {
+	if errors.Is(err, service.ErrNotExists) {
+		return c.SetStatus(http.StatusNotFound).SendJSON(Response{
+			Message: "Resource not found",
+			Error:   err.Error(),
+		})
+	}
+
+	if errors.Is(err, service.ErrBadRequest) {
+		return c.SetStatus(http.StatusBadRequest).SendJSON(Response{
+			Message: badRequestMessage,
+			Error:   err.Error(),
+		})
+	}
+
+	if errors.Is(err, service.ErrForbidden) {
+		return c.SetStatus(http.StatusForbidden).SendJSON(Response{
+			Message: "Database is read only",
+			Error:   err.Error(),
+		})
+	}
+
+	if errors.Is(err, service.ErrUnavailable) {
+		return c.SetStatus(http.StatusServiceUnavailable).SendJSON(Response{
+			Message: "Database is unavailable",
+			Error:   err.Error(),
+		})
+	}
+
+	if errors.Is(err, service.ErrTimeout) {
+		return c.SetStatus(http.StatusGatewayTimeout).SendJSON(Response{
+			Message: "Query timed out",
+			Error:   err.Error(),
+		})
+	}
+
+	return c.SetStatus(http.StatusInternalServerError).SendJSON(Response{
+		Message: "Failed to execute query",
+		Error:   err.Error(),
 	})
 }
 
@@ -91,24 +159,7 @@ func (s *Server) runNote(c *ada.Context) error {
 	}
 
 	if err := s.service.RunNote(ctx, noteName, values); err != nil {
-		if errors.Is(err, service.ErrNotExists) {
-			return c.SetStatus(http.StatusNotFound).SendJSON(Response{
-				Message: "Resource not found",
-				Error:   err.Error(),
-			})
-		}
-
-		if errors.Is(err, service.ErrBadRequest) {
-			return c.SetStatus(http.StatusBadRequest).SendJSON(Response{
-				Message: "Invalid note name",
-				Error:   err.Error(),
-			})
-		}
-
-		return c.SetStatus(http.StatusInternalServerError).SendJSON(Response{
-			Message: "Failed to execute query",
-			Error:   err.Error(),
-		})
+		return sendRunError(c, err, "Invalid note name")
 	}
 
 	return c.SetStatus(http.StatusOK).SendJSON(Response{
@@ -122,6 +173,8 @@ func (s *Server) runNoteCell(c *ada.Context) error {
 	noteName := c.Request.PathValue("note")
 	cellPath := c.Request.PathValue("cell")
 
+	format, stream := responseFormat(c.Request)
+
 	values, err := getValuesFromRequest(c.Request)
 	if err != nil {
 		return c.SetStatus(http.StatusBadRequest).SendJSON(Response{
@@ -130,47 +183,91 @@ func (s *Server) runNoteCell(c *ada.Context) error {
 		})
 	}
 
+	if stream {
+		// canceled when the client disconnects
+		rowStream, err := s.service.RunNoteCellStream(c.Request.Context(), noteName, cellPath, values)
+		if err != nil {
+			return sendRunError(c, err, "Invalid note or cell")
+		}
+
+		return sendStream(c, format, rowStream)
+	}
+
 	result, err := s.service.RunNoteCell(ctx, noteName, cellPath, values)
 	if err != nil {
-		if errors.Is(err, service.ErrNotExists) {
-			return c.SetStatus(http.StatusNotFound).SendJSON(Response{
-				Message: "Resource not found",
-				Error:   err.Error(),
-			})
-		}
+		return sendRunError(c, err, "Invalid note or cell")
+	}
 
-		if errors.Is(err, service.ErrBadRequest) {
-			return c.SetStatus(http.StatusBadRequest).SendJSON(Response{
-				Message: "Invalid note or cell",
-				Error:   err.Error(),
-			})
-		}
+	if format != "" {
+		return sendFile(c, format, noteName+"_"+cellPath, result)
+	}
 
-		return c.SetStatus(http.StatusInternalServerError).SendJSON(Response{
-			Message: "Failed to execute query",
-			Error:   err.Error(),
-		})
+	return c.SetStatus(http.StatusOK).SendJSON(responseQuery(result))
+}
+
+func (s *Server) getPage(c *ada.Context) error {
+	result, err := s.service.NextPage(c.Request.Context(), c.Request.PathValue("token"))
+	if err != nil {
+		return sendRunError(c, err, "Invalid page")
 	}
 
-	return c.SetStatus(http.StatusOK).SendJSON(ResponseQuery{
-		RowsAffected: result.RowsAffected(),
-		Columns:      result.Columns(),
-		Rows:         result.Rows(),
-		Duration:     result.Duration().Truncate(time.Microsecond).String(),
+	return c.SetStatus(http.StatusOK).SendJSON(responseQuery(result))
+}
+
+func (s *Server) deletePage(c *ada.Context) error {
+	if err := s.service.ClosePage(c.Request.Context(), c.Request.PathValue("token")); err != nil {
+		return sendRunError(c, err, "Invalid page")
+	}
+
+	return c.SetStatus(http.StatusOK).SendJSON(Response{
+		Message: "Page closed successfully",
+	})
+}
+
+// deleteCache removes cached results, db_type, note and cell query parameters select them.
+func (s *Server) deleteCache(c *ada.Context) error {
+	query := c.Request.URL.Query()
+
+	removed := s.service.InvalidateCache(c.Request.Context(), service.CacheFilter{
+		DBType: query.Get("db_type"),
+		Note:   query.Get("note"),
+		Cell:   query.Get("cell"),
+	})
+
+	return c.SetStatus(http.StatusOK).SendJSON(Response{
+		Message: "Cache invalidated",
+		Data:    map[string]int{"removed": removed},
 	})
 }
 
 func (s *Server) info(c *ada.Context) error {
-	dbList := s.service.DatabaseList()
+	status := s.service.DatabaseList()
+
+	dbList := make([]string, 0, len(status))
+	var readOnly []string
+	for _, db := range status {
+		dbList = append(dbList, db.Name)
+		if db.ReadOnly {
+			readOnly = append(readOnly, db.Name)
+		}
+	}
 
 	return c.SetStatus(http.StatusOK).SendJSON(Response{
 		Data: Info{
 			Databases: dbList,
+			ReadOnly:  readOnly,
+			Status:    status,
 			Version:   config.ServerVersion,
 		},
 	})
 }
 
+func (s *Server) getPools(c *ada.Context) error {
+	return c.SetStatus(http.StatusOK).SendJSON(Response{
+		Data: s.service.PoolStats(),
+	})
+}
+
 func (s *Server) putNote(c *ada.Context) error {
 	var note service.Note
 	if err := json.NewDecoder(c.Request.Body).Decode(&note); err != nil {
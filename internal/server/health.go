@@ -0,0 +1,39 @@
+package server
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/rakunlabs/ada"
+	"github.com/worldline-go/saz/internal/service"
+)
+
+// healthz reports only that the process responds, components don't restart a healthy service.
+func (s *Server) healthz(c *ada.Context) error {
+	return c.SetStatus(http.StatusOK).SendJSON(service.Health{Status: service.HealthUp})
+}
+
+// readyz is unavailable when the store or a required database is down.
+// Components with their errors are only returned with the private token, probes get the status.
+func (s *Server) readyz(c *ada.Context) error {
+	health := s.service.Health(c.Request.Context())
+
+	if !s.privateRequest(c.Request) {
+		health.Components = nil
+	}
+
+	if health.Status == service.HealthDown {
+		return c.SetStatus(http.StatusServiceUnavailable).SendJSON(health)
+	}
+
+	return c.SetStatus(http.StatusOK).SendJSON(health)
+}
+
+// privateRequest reports whether the request has the private token, every request has it without private token.
+func (s *Server) privateRequest(r *http.Request) bool {
+	if s.config.PrivateToken == "" {
+		return true
+	}
+
+	return subtle.ConstantTimeCompare([]byte(r.Header.Get("Private-Token")), []byte(s.config.PrivateToken)) == 1
+}
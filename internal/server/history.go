@@ -0,0 +1,70 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/rakunlabs/ada"
+	"github.com/worldline-go/saz/internal/service"
+)
+
+func (s *Server) getHistory(c *ada.Context) error {
+	filter, err := historyFilter(c.Request.URL.Query())
+	if err != nil {
+		return c.SetStatus(http.StatusBadRequest).SendJSON(Response{
+			Message: "Invalid history filter",
+			Error:   err.Error(),
+		})
+	}
+
+	histories, err := s.service.GetHistory(c.Request.Context(), filter)
+	if err != nil {
+		return sendRunError(c, err, "Invalid history filter")
+	}
+
+	return c.SetStatus(http.StatusOK).SendJSON(Response{
+		Data: histories,
+	})
+}
+
+// historyFilter reads user, db_type, note, from, to (RFC3339), limit and offset query parameters.
+func historyFilter(query url.Values) (service.HistoryFilter, error) {
+	filter := service.HistoryFilter{
+		User:   query.Get("user"),
+		DBType: query.Get("db_type"),
+		Note:   query.Get("note"),
+	}
+
+	for _, v := range []struct {
+		name string
+		dst  *time.Time
+	}{{"from", &filter.From}, {"to", &filter.To}} {
+		if value := query.Get(v.name); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return filter, fmt.Errorf("%s is not RFC3339: %w", v.name, err)
+			}
+
+			*v.dst = t
+		}
+	}
+
+	for _, v := range []struct {
+		name string
+		dst  *int
+	}{{"limit", &filter.Limit}, {"offset", &filter.Offset}} {
+		if value := query.Get(v.name); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return filter, fmt.Errorf("%s is not a number: %w", v.name, err)
+			}
+
+			*v.dst = n
+		}
+	}
+
+	return filter, nil
+}
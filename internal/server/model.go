@@ -1,5 +1,7 @@
 package server
 
+import "github.com/worldline-go/saz/internal/service"
+
 type Response struct {
 	Message string `json:"message,omitempty"`
 	Error   string `json:"error,omitempty"`
@@ -7,15 +9,28 @@ type Response struct {
 }
 
 type ResponseQuery struct {
-	Columns      []string `json:"columns,omitempty"`
-	Rows         [][]any  `json:"rows,omitempty"`
-	RowsAffected int64    `json:"rows_affected,omitempty"`
-	Duration     string   `json:"duration,omitempty"`
+	Columns      []string             `json:"columns,omitempty"`
+	ColumnTypes  []service.ColumnInfo `json:"column_types,omitempty"`
+	Rows         [][]any              `json:"rows,omitempty"`
+	RowsAffected int64                `json:"rows_affected,omitempty"`
+	Duration     string               `json:"duration,omitempty"`
+	// Results are result sets of script cells.
+	Results []ResponseQuery `json:"results,omitempty"`
+	// NextPage is the token of the next page of a paged query.
+	NextPage string `json:"next_page,omitempty"`
+	// Plan is the normalized plan of an explained query.
+	Plan *service.PlanNode `json:"plan,omitempty"`
+	// Cache is set for cells with cache.
+	Cache *service.CacheInfo `json:"cache,omitempty"`
 }
 
 type Info struct {
 	Databases []string `json:"databases"`
-	Version   string   `json:"version"`
+	// ReadOnly are the databases which only allow reads.
+	ReadOnly []string `json:"read_only,omitempty"`
+	// Status is the availability of the databases.
+	Status  []service.DatabaseStatus `json:"status"`
+	Version string                   `json:"version"`
 }
 
 type RenderRequest struct {
@@ -6,6 +6,7 @@ import (
 	"io/fs"
 	"net"
 	"net/http"
+	"path"
 
 	"github.com/rakunlabs/ada"
 	mfolder "github.com/rakunlabs/ada/handler/folder"
@@ -31,6 +32,11 @@ var uiFS embed.FS
 
 func New(ctx context.Context, cfg config.Server, svc *service.Service) (*Server, error) {
 	privateToken := cfg.PrivateToken
+	// probes don't have the token
+	probes := map[string]struct{}{
+		path.Join("/", cfg.BasePath, "healthz"): {},
+		path.Join("/", cfg.BasePath, "readyz"):  {},
+	}
 
 	mux := ada.New()
 	mux.Use(
@@ -41,10 +47,11 @@ func New(ctx context.Context, cfg config.Server, svc *service.Service) (*Server,
 		mtelemetry.Middleware(),
 		func(next http.Handler) http.Handler {
 			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-				if privateToken != "" {
+				_, probe := probes[r.URL.Path]
+				if privateToken != "" && !probe {
 					token := r.Header.Get("Private-Token")
 					if token == privateToken {
-						next.ServeHTTP(w, r)
+						next.ServeHTTP(w, r.WithContext(Context(r)))
 						return
 					}
 
@@ -71,6 +78,9 @@ func New(ctx context.Context, cfg config.Server, svc *service.Service) (*Server,
 	// ////////////////////////////////////////////
 
 	baseGroup := mux.Group(cfg.BasePath)
+	baseGroup.GET("/healthz", baseGroup.Wrap(s.healthz))
+	baseGroup.GET("/readyz", baseGroup.Wrap(s.readyz))
+
 	baseGroup.POST("/api/v1/run", baseGroup.Wrap(s.run))
 	baseGroup.POST("/api/v1/run/{note}", baseGroup.Wrap(s.runNote))
 	baseGroup.GET("/api/v1/run/{note}", baseGroup.Wrap(s.runNote))
@@ -78,13 +88,25 @@ func New(ctx context.Context, cfg config.Server, svc *service.Service) (*Server,
 	baseGroup.POST("/api/v1/run/{note}/{cell}", baseGroup.Wrap(s.runNoteCell))
 	baseGroup.GET("/api/v1/run/{note}/{cell}", baseGroup.Wrap(s.runNoteCell))
 
+	baseGroup.GET("/api/v1/pages/{token}", baseGroup.Wrap(s.getPage))
+	baseGroup.DELETE("/api/v1/pages/{token}", baseGroup.Wrap(s.deletePage))
+
+	baseGroup.GET("/api/v1/history", baseGroup.Wrap(s.getHistory))
+	baseGroup.DELETE("/api/v1/cache", baseGroup.Wrap(s.deleteCache))
+
 	baseGroup.GET("/api/v1/info", baseGroup.Wrap(s.info))
+	baseGroup.GET("/api/v1/pools", baseGroup.Wrap(s.getPools))
 	baseGroup.GET("/api/v1/notes", baseGroup.Wrap(s.getNotes))
 	baseGroup.GET("/api/v1/notes/{id}", baseGroup.Wrap(s.getNote))
 	baseGroup.PUT("/api/v1/notes/{id}", baseGroup.Wrap(s.putNote))
 	baseGroup.DELETE("/api/v1/notes/{id}", baseGroup.Wrap(s.deleteNote))
 	baseGroup.POST("/api/v1/render", baseGroup.Wrap(s.render))
 
+	baseGroup.GET("/api/v1/admin/databases", baseGroup.Wrap(s.admin(s.getConnections)))
+	baseGroup.PUT("/api/v1/admin/databases/{name}", baseGroup.Wrap(s.admin(s.putConnection)))
+	baseGroup.DELETE("/api/v1/admin/databases/{name}", baseGroup.Wrap(s.admin(s.deleteConnection)))
+	baseGroup.POST("/api/v1/admin/databases/{name}/test", baseGroup.Wrap(s.admin(s.testConnection)))
+
 	// ////////////////////////////////////////////
 
 	f, err := fs.Sub(uiFS, "dist")
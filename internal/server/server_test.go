@@ -0,0 +1,116 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/worldline-go/saz/internal/config"
+	"github.com/worldline-go/saz/internal/service"
+)
+
+// historyStore keeps added records, other store calls are not used.
+type historyStore struct {
+	service.Storer
+
+	histories []*service.History
+}
+
+func (s *historyStore) AddHistory(_ context.Context, history *service.History) error {
+	s.histories = append(s.histories, history)
+
+	return nil
+}
+
+// rowResult is a query result of a single row.
+type rowResult struct{}
+
+func (rowResult) Columns() []string                 { return []string{"n"} }
+func (rowResult) ColumnTypes() []service.ColumnInfo { return nil }
+func (rowResult) Rows() [][]any                     { return [][]any{{1}} }
+func (rowResult) RowsAffected() int64               { return 0 }
+func (rowResult) Duration() time.Duration           { return 0 }
+
+// queryDatabase returns one row for every query.
+type queryDatabase struct {
+	service.Database
+}
+
+func (d *queryDatabase) QueryTimeout(string) time.Duration {
+	return 0
+}
+
+func (d *queryDatabase) Query(context.Context, string, string, map[string]any, int64, bool, service.Snapshot) (service.Result, error) {
+	return rowResult{}, nil
+}
+
+func TestPrivateTokenUser(t *testing.T) {
+	store := &historyStore{}
+
+	s, err := New(t.Context(), config.Server{PrivateToken: "secret"}, service.New(&queryDatabase{}, store))
+	require.NoError(t, err)
+
+	run := func(token string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/run", strings.NewReader(`{"db_type": "replica", "content": "SELECT 1", "result": true, "values": {}}`))
+		req.Header.Set("Private-Token", token)
+		req.Header.Set("X-User", "alice")
+
+		rec := httptest.NewRecorder()
+		s.server.ServeHTTP(rec, req)
+
+		return rec.Code
+	}
+
+	require.Equal(t, http.StatusForbidden, run("wrong"))
+	require.Empty(t, store.histories)
+
+	require.Equal(t, http.StatusOK, run("secret"))
+	require.Len(t, store.histories, 1)
+	require.Equal(t, "alice", store.histories[0].User)
+}
+
+func TestResponseFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		accept string
+		format string
+		stream bool
+	}{
+		{name: "default", target: "/", format: ""},
+		{name: "stream", target: "/?stream=ndjson", format: "ndjson", stream: true},
+		{name: "format", target: "/?format=XLSX", format: "xlsx"},
+		{name: "accept returns a file", target: "/", accept: "text/csv", format: "csv"},
+		{name: "accept with stream", target: "/?stream=csv", accept: "application/vnd.apache.parquet", format: "csv", stream: true},
+		{name: "format wins over accept", target: "/?format=tsv", accept: "text/csv", format: "tsv"},
+		{name: "accept json", target: "/", accept: "application/json", format: ""},
+		{name: "unsupported is cell data", target: "/?format=summary", format: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+
+			format, stream := responseFormat(req)
+			require.Equal(t, tt.format, format)
+			require.Equal(t, tt.stream, stream)
+		})
+	}
+}
+
+func TestGetValuesFromRequest(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/?status=active&format=summary&stream=csv", nil)
+
+	values, err := getValuesFromRequest(req)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{
+		"data": map[string]any{"status": "active", "format": "summary"},
+	}, values)
+}
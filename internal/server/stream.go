@@ -0,0 +1,132 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+
+	"github.com/rakunlabs/ada"
+	"github.com/worldline-go/saz/internal/export"
+	"github.com/worldline-go/saz/internal/service"
+)
+
+// streamFlushRows is the number of rows written before flushing to the client.
+const streamFlushRows = 100
+
+// trailerStreamError reports an error after the rows are started to write.
+const trailerStreamError = "X-Stream-Error"
+
+// responseFormat returns the requested format of the result and whether rows are streamed, empty format for the JSON response.
+//   - stream query parameter streams in the format
+//   - format query parameter or Accept header of a format returns the whole result as a file
+func responseFormat(r *http.Request) (string, bool) {
+	query := r.URL.Query()
+
+	if format, ok := formatParam("stream", query.Get("stream")); ok {
+		return format, true
+	}
+
+	if format, ok := formatParam("format", query.Get("format")); ok {
+		return format, false
+	}
+
+	if format := export.FromAccept(r.Header.Get("Accept")); format != "" {
+		return format, false
+	}
+
+	return "", false
+}
+
+// formatParam returns the format of the stream and format query parameters.
+// Values which are not a supported format are cell data like other query parameters.
+func formatParam(key, value string) (string, bool) {
+	if key != "stream" && key != "format" {
+		return "", false
+	}
+
+	format, err := export.Check(value)
+	if err != nil {
+		return "", false
+	}
+
+	return format, true
+}
+
+// sendStream writes rows of the stream while they are read.
+// Errors after the header are written to the X-Stream-Error trailer and in the body of JSON formats.
+func sendStream(c *ada.Context, format string, stream *service.RowStream) error {
+	writer, err := export.New(format, c.Response)
+	if err != nil {
+		// close the query
+		for range stream.Rows {
+			break
+		}
+
+		return sendRunError(c, err, "Invalid format")
+	}
+
+	controller := http.NewResponseController(c.Response)
+
+	c.Response.Header().Set("Content-Type", export.ContentType(format))
+	c.Response.Header().Set("Trailer", trailerStreamError)
+	c.Response.WriteHeader(http.StatusOK)
+
+	streamErr := writer.Header(stream.Columns, stream.ColumnTypes)
+
+	count := 0
+	for row, err := range stream.Rows {
+		if streamErr != nil {
+			// stop the query, client is gone
+			break
+		}
+
+		if err != nil {
+			streamErr = err
+
+			break
+		}
+
+		if err := writer.Row(row); err != nil {
+			streamErr = err
+
+			break
+		}
+
+		count++
+		if count%streamFlushRows == 0 {
+			if err := controller.Flush(); err != nil {
+				streamErr = err
+
+				break
+			}
+		}
+	}
+
+	if streamErr != nil {
+		c.Response.Header().Set(trailerStreamError, streamErr.Error())
+	}
+
+	_ = writer.End(streamErr)
+	_ = controller.Flush()
+
+	return nil
+}
+
+// sendFile sends the result as an attachment in the format, JSON is the default response.
+func sendFile(c *ada.Context, format, name string, result service.Result) error {
+	if format == export.FormatJSON {
+		return c.SetStatus(http.StatusOK).SendJSON(responseQuery(result))
+	}
+
+	var buf bytes.Buffer
+	if err := export.Write(&buf, format, result); err != nil {
+		return c.SetStatus(http.StatusInternalServerError).SendJSON(Response{
+			Message: "Failed to export result",
+			Error:   err.Error(),
+		})
+	}
+
+	c.Response.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+"."+format))
+
+	return c.SetStatus(http.StatusOK).SetHeader("Content-Type", export.ContentType(format)).SendBlob(&buf)
+}
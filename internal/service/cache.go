@@ -0,0 +1,281 @@
+package service
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultCacheTTL        = time.Minute
+	DefaultCacheMaxEntries = 1000
+	DefaultCacheMaxBytes   = 64 << 20
+)
+
+// CacheInfo is the cache state of a result.
+type CacheInfo struct {
+	// Hit is true when the result is read from the cache.
+	Hit       bool      `json:"hit"`
+	Key       string    `json:"key"`
+	CachedAt  time.Time `json:"cached_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// CachedResult is the result of a cell with cache.
+type CachedResult interface {
+	Result
+	Cache() CacheInfo
+}
+
+// CacheFilter selects cache entries to invalidate, empty fields match all.
+type CacheFilter struct {
+	DBType string
+	Note   string
+	Cell   string
+}
+
+// ResultCache keeps results of query cells in memory, least recently used entries are evicted over the limits.
+type ResultCache struct {
+	// TTL of cells without own ttl.
+	TTL time.Duration
+	// MaxEntries is the number of cached results.
+	MaxEntries int
+	// MaxBytes is the estimated size of all cached rows, bigger results are not cached.
+	MaxBytes int64
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     list.List
+	size    int64
+}
+
+type cacheEntry struct {
+	key    string
+	filter CacheFilter
+	result *cacheResult
+	size   int64
+}
+
+type cacheResult struct {
+	columns      []string
+	columnTypes  []ColumnInfo
+	rows         [][]any
+	rowsAffected int64
+	duration     time.Duration
+	info         CacheInfo
+}
+
+func (r *cacheResult) Columns() []string         { return r.columns }
+func (r *cacheResult) ColumnTypes() []ColumnInfo { return r.columnTypes }
+func (r *cacheResult) Rows() [][]any             { return r.rows }
+func (r *cacheResult) RowsAffected() int64       { return r.rowsAffected }
+func (r *cacheResult) Duration() time.Duration   { return r.duration }
+func (r *cacheResult) Cache() CacheInfo          { return r.info }
+
+func (c *ResultCache) ttl() time.Duration {
+	if c.TTL <= 0 {
+		return DefaultCacheTTL
+	}
+
+	return c.TTL
+}
+
+func (c *ResultCache) maxEntries() int {
+	if c.MaxEntries <= 0 {
+		return DefaultCacheMaxEntries
+	}
+
+	return c.MaxEntries
+}
+
+func (c *ResultCache) maxBytes() int64 {
+	if c.MaxBytes <= 0 {
+		return DefaultCacheMaxBytes
+	}
+
+	return c.MaxBytes
+}
+
+// cacheKey returns the key of the query, params are encoded with sorted keys.
+func cacheKey(cell *Cell, content string, params map[string]any) (string, error) {
+	paramsJSON, err := json.Marshal(params)
+	if err != nil {
+		return "", fmt.Errorf("encode params of cache key: %w", err)
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%s\x00%d\x00%t", cell.DBType, content, paramsJSON, cell.Limit, cell.Typed.V)
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// cacheTTL returns the ttl of the cell.
+func (c *ResultCache) cacheTTL(cell *Cell) (time.Duration, error) {
+	if cell.Cache.TTL == "" {
+		return c.ttl(), nil
+	}
+
+	ttl, err := time.ParseDuration(cell.Cache.TTL)
+	if err != nil || ttl <= 0 {
+		return 0, fmt.Errorf("invalid cache ttl %q; %w", cell.Cache.TTL, ErrBadRequest)
+	}
+
+	return ttl, nil
+}
+
+// Get returns the cached result of the key when it is not expired.
+func (c *ResultCache) Get(key string) (CachedResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := elem.Value.(*cacheEntry)
+	if time.Now().After(entry.result.info.ExpiresAt) {
+		c.remove(elem)
+
+		return nil, false
+	}
+
+	c.lru.MoveToFront(elem)
+
+	// rows are shared, only the cache info is per response
+	hit := *entry.result
+	hit.info.Hit = true
+
+	return &hit, true
+}
+
+// Set caches the result and returns it with its cache info, results over the size limit are returned uncached.
+func (c *ResultCache) Set(key string, filter CacheFilter, result Result, ttl time.Duration) Result {
+	size := resultSize(result)
+	if size > c.maxBytes() {
+		return result
+	}
+
+	now := time.Now()
+	entry := &cacheEntry{
+		key:    key,
+		filter: filter,
+		size:   size,
+		result: &cacheResult{
+			columns:      result.Columns(),
+			columnTypes:  result.ColumnTypes(),
+			rows:         result.Rows(),
+			rowsAffected: result.RowsAffected(),
+			duration:     result.Duration(),
+			info: CacheInfo{
+				Key:       key,
+				CachedAt:  now,
+				ExpiresAt: now.Add(ttl),
+			},
+		},
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entries == nil {
+		c.entries = make(map[string]*list.Element)
+	}
+
+	if elem, ok := c.entries[key]; ok {
+		c.remove(elem)
+	}
+
+	c.entries[key] = c.lru.PushFront(entry)
+	c.size += size
+
+	for len(c.entries) > c.maxEntries() || c.size > c.maxBytes() {
+		c.remove(c.lru.Back())
+	}
+
+	miss := *entry.result
+
+	return &miss
+}
+
+// Invalidate removes the entries matching the filter and returns their count.
+func (c *ResultCache) Invalidate(filter CacheFilter) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var removed int
+	for elem := c.lru.Front(); elem != nil; {
+		next := elem.Next()
+
+		entry := elem.Value.(*cacheEntry)
+		if (filter.DBType == "" || filter.DBType == entry.filter.DBType) &&
+			(filter.Note == "" || filter.Note == entry.filter.Note) &&
+			(filter.Cell == "" || filter.Cell == entry.filter.Cell) {
+			c.remove(elem)
+			removed++
+		}
+
+		elem = next
+	}
+
+	return removed
+}
+
+func (c *ResultCache) remove(elem *list.Element) {
+	entry := c.lru.Remove(elem).(*cacheEntry)
+	delete(c.entries, entry.key)
+	c.size -= entry.size
+}
+
+// resultSize estimates the memory of the rows of the result.
+func resultSize(result Result) int64 {
+	var size int64
+	for _, row := range result.Rows() {
+		for _, v := range row {
+			switch val := v.(type) {
+			case string:
+				size += int64(len(val))
+			case []byte:
+				size += int64(len(val))
+			default:
+				size += 16
+			}
+		}
+	}
+
+	return size
+}
+
+// cachedQuery returns the cached result of the query cell or runs the query and caches it.
+func (s *Service) cachedQuery(ctx context.Context, cell *Cell, content string, cellParams map[string]any) (Result, error) {
+	ttl, err := s.Cache.cacheTTL(cell)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := cacheKey(cell, content, cellParams)
+	if err != nil {
+		return nil, err
+	}
+
+	if result, ok := s.Cache.Get(key); ok {
+		return result, nil
+	}
+
+	result, err := s.db.Query(ctx, cell.DBType, content, cellParams, cell.Limit, cell.Typed.V, cell.Snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Cache.Set(key, CacheFilter{DBType: cell.DBType, Note: NoteContext(ctx), Cell: cell.Path.V}, result, ttl), nil
+}
+
+// InvalidateCache removes cached results matching the filter and returns their count.
+func (s *Service) InvalidateCache(_ context.Context, filter CacheFilter) int {
+	return s.Cache.Invalidate(filter)
+}
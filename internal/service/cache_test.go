@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// countDatabase returns the id param as the row and counts queries.
+type countDatabase struct {
+	Database
+
+	queries int
+}
+
+func (d *countDatabase) QueryTimeout(string) time.Duration {
+	return 0
+}
+
+func (d *countDatabase) Query(_ context.Context, _, _ string, params map[string]any, _ int64, _ bool, _ Snapshot) (Result, error) {
+	d.queries++
+
+	return &TransferResult{columns: []string{"id"}, rows: [][]any{{params["id"]}}}, nil
+}
+
+func cacheCell(ttl string) *Cell {
+	cell := &Cell{
+		DBType:  "db",
+		Content: "SELECT :id",
+		Params:  []Param{{Name: "id", Path: "id"}},
+		Cache:   Cache{Enabled: true, TTL: ttl},
+	}
+	cell.Result.V = true
+	cell.Path.V = "users"
+
+	return cell
+}
+
+func TestCache(t *testing.T) {
+	t.Run("hit and params", func(t *testing.T) {
+		db := &countDatabase{}
+		s := New(db, nil)
+		cell := cacheCell("")
+
+		result, err := s.Run(t.Context(), cell, map[string]any{"id": "1"}, nil)
+		require.NoError(t, err)
+		require.False(t, result.(CachedResult).Cache().Hit)
+
+		result, err = s.Run(t.Context(), cell, map[string]any{"id": "1"}, nil)
+		require.NoError(t, err)
+		require.True(t, result.(CachedResult).Cache().Hit)
+		require.Equal(t, [][]any{{"1"}}, result.Rows())
+		require.Equal(t, 1, db.queries)
+
+		_, err = s.Run(t.Context(), cell, map[string]any{"id": "2"}, nil)
+		require.NoError(t, err)
+		require.Equal(t, 2, db.queries)
+
+		// cache is opt-in
+		cell.Cache.Enabled = false
+		result, err = s.Run(t.Context(), cell, map[string]any{"id": "1"}, nil)
+		require.NoError(t, err)
+		require.NotImplements(t, (*CachedResult)(nil), result)
+		require.Equal(t, 3, db.queries)
+	})
+
+	t.Run("ttl", func(t *testing.T) {
+		db := &countDatabase{}
+		s := New(db, nil)
+		cell := cacheCell("10ms")
+
+		_, err := s.Run(t.Context(), cell, map[string]any{"id": "1"}, nil)
+		require.NoError(t, err)
+
+		time.Sleep(20 * time.Millisecond)
+
+		result, err := s.Run(t.Context(), cell, map[string]any{"id": "1"}, nil)
+		require.NoError(t, err)
+		require.False(t, result.(CachedResult).Cache().Hit)
+		require.Equal(t, 2, db.queries)
+
+		_, err = s.Run(t.Context(), cacheCell("soon"), map[string]any{"id": "1"}, nil)
+		require.ErrorIs(t, err, ErrBadRequest)
+	})
+
+	t.Run("invalidate", func(t *testing.T) {
+		db := &countDatabase{}
+		s := New(db, nil)
+		cell := cacheCell("")
+
+		_, err := s.Run(ContextWithNote(t.Context(), "reports"), cell, map[string]any{"id": "1"}, nil)
+		require.NoError(t, err)
+
+		require.Zero(t, s.InvalidateCache(t.Context(), CacheFilter{Note: "other"}))
+		require.Equal(t, 1, s.InvalidateCache(t.Context(), CacheFilter{Note: "reports", Cell: "users"}))
+
+		_, err = s.Run(t.Context(), cell, map[string]any{"id": "1"}, nil)
+		require.NoError(t, err)
+		require.Equal(t, 2, db.queries)
+	})
+
+	t.Run("limits", func(t *testing.T) {
+		db := &countDatabase{}
+		s := New(db, nil)
+		s.Cache.MaxEntries = 1
+
+		cell := cacheCell("")
+		for _, id := range []string{"1", "2", "1"} {
+			_, err := s.Run(t.Context(), cell, map[string]any{"id": id}, nil)
+			require.NoError(t, err)
+		}
+
+		// first entry is evicted by the second one
+		require.Equal(t, 3, db.queries)
+
+		s.Cache.MaxBytes = 4
+		result, err := s.Run(t.Context(), cell, map[string]any{"id": "too big"}, nil)
+		require.NoError(t, err)
+		require.NotImplements(t, (*CachedResult)(nil), result)
+	})
+}
@@ -0,0 +1,422 @@
+package service
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rakunlabs/logi"
+	"github.com/worldline-go/types"
+)
+
+const (
+	ConnectionConfig = "config"
+	ConnectionStore  = "store"
+)
+
+var connectionName = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// Connection is a database managed at runtime.
+type Connection struct {
+	Name   string `json:"name"`
+	DBType string `json:"db_type"`
+	// Datasource is only set in requests, an update without it keeps the saved one.
+	Datasource string `json:"datasource,omitempty"`
+	DBSchema   string `json:"db_schema,omitempty"`
+	// InitStatements run on every new connection after the schema.
+	InitStatements []string `json:"init_statements,omitempty"`
+	// QueryTimeout, ConnMaxLifetime and ConnMaxIdleTime are durations like 30s.
+	QueryTimeout    string `json:"query_timeout,omitempty"`
+	ReadOnly        bool   `json:"read_only,omitempty"`
+	MaxOpenConns    int    `json:"max_open_conns,omitempty"`
+	MaxIdleConns    int    `json:"max_idle_conns,omitempty"`
+	ConnMaxLifetime string `json:"conn_max_lifetime,omitempty"`
+	ConnMaxIdleTime string `json:"conn_max_idle_time,omitempty"`
+
+	// Source is config for databases of the config file, they can't be changed at runtime.
+	Source    string                 `json:"source,omitempty"`
+	UpdatedBy types.Null[string]     `json:"updated_by,omitzero"`
+	CreatedAt types.Null[types.Time] `json:"created_at,omitzero"`
+	UpdatedAt types.Null[types.Time] `json:"updated_at,omitzero"`
+}
+
+// Durations returns the query timeout, connection max lifetime and max idle time.
+func (c *Connection) Durations() (queryTimeout, maxLifetime, maxIdleTime time.Duration, err error) {
+	values := []struct {
+		name  string
+		value string
+		to    *time.Duration
+	}{
+		{"query_timeout", c.QueryTimeout, &queryTimeout},
+		{"conn_max_lifetime", c.ConnMaxLifetime, &maxLifetime},
+		{"conn_max_idle_time", c.ConnMaxIdleTime, &maxIdleTime},
+	}
+
+	for _, v := range values {
+		if v.value == "" {
+			continue
+		}
+
+		d, err := time.ParseDuration(v.value)
+		if err != nil || d < 0 {
+			return 0, 0, 0, fmt.Errorf("invalid %s %q; %w", v.name, v.value, ErrBadRequest)
+		}
+
+		*v.to = d
+	}
+
+	return queryTimeout, maxLifetime, maxIdleTime, nil
+}
+
+// Validate checks the settings of the connection, the datasource is checked by the caller.
+func (c *Connection) Validate() error {
+	if !connectionName.MatchString(c.Name) {
+		return fmt.Errorf("invalid database name %q, use letters, digits, '_', '.' and '-'; %w", c.Name, ErrBadRequest)
+	}
+
+	if c.DBType == "" {
+		return fmt.Errorf("db_type is required; %w", ErrBadRequest)
+	}
+
+	if c.MaxOpenConns < 0 || c.MaxIdleConns < 0 {
+		return fmt.Errorf("pool sizes can't be negative; %w", ErrBadRequest)
+	}
+
+	_, _, _, err := c.Durations()
+
+	return err
+}
+
+// Secret encrypts datasources of connections with AES-256-GCM, the key is hashed to the cipher key.
+type Secret struct {
+	aead cipher.AEAD
+}
+
+func NewSecret(key string) (*Secret, error) {
+	if key == "" {
+		return nil, errors.New("secret key is empty")
+	}
+
+	sum := sha256.Sum256([]byte(key))
+
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, fmt.Errorf("create cipher: %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("create gcm: %w", err)
+	}
+
+	return &Secret{aead: aead}, nil
+}
+
+// Encrypt returns the base64 of the nonce and the sealed text.
+func (s *Secret) Encrypt(text string) (string, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("create nonce: %w", err)
+	}
+
+	return base64.StdEncoding.EncodeToString(s.aead.Seal(nonce, nonce, []byte(text), nil)), nil
+}
+
+func (s *Secret) Decrypt(text string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(text)
+	if err != nil {
+		return "", fmt.Errorf("decode secret: %w", err)
+	}
+
+	if len(data) < s.aead.NonceSize() {
+		return "", errors.New("secret is too short")
+	}
+
+	nonce, sealed := data[:s.aead.NonceSize()], data[s.aead.NonceSize():]
+
+	plain, err := s.aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return "", fmt.Errorf("decrypt secret, key may be changed: %w", err)
+	}
+
+	return string(plain), nil
+}
+
+// connectionLocks serializes changes of connections by name, the store and the runtime database are changed together.
+type connectionLocks struct {
+	mu    sync.Mutex
+	locks map[string]*connectionLock
+}
+
+type connectionLock struct {
+	mu      sync.Mutex
+	waiters int
+}
+
+// lock locks the name and returns the unlock.
+func (l *connectionLocks) lock(name string) func() {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = make(map[string]*connectionLock)
+	}
+
+	lock, ok := l.locks[name]
+	if !ok {
+		lock = &connectionLock{}
+		l.locks[name] = lock
+	}
+	lock.waiters++
+	l.mu.Unlock()
+
+	lock.mu.Lock()
+
+	return func() {
+		lock.mu.Unlock()
+
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		if lock.waiters--; lock.waiters == 0 {
+			delete(l.locks, name)
+		}
+	}
+}
+
+// configDatabase reports whether the database is from the config file.
+func (s *Service) configDatabase(ctx context.Context, name string) (bool, error) {
+	if _, err := s.store.GetConnection(ctx, name); err == nil || !errors.Is(err, ErrNotExists) {
+		return false, err
+	}
+
+	return slices.ContainsFunc(s.db.DatabaseList(), func(db DatabaseStatus) bool {
+		return db.Name == name
+	}), nil
+}
+
+// savedConnection returns the saved connection with its datasource decrypted, nil if it is not saved.
+func (s *Service) savedConnection(ctx context.Context, name string) (*Connection, error) {
+	if s.Secret == nil {
+		return nil, fmt.Errorf("secret key is not configured; %w", ErrBadRequest)
+	}
+
+	isConfig, err := s.configDatabase(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if isConfig {
+		return nil, fmt.Errorf("database %s is in the config file, it can't be changed; %w", name, ErrBadRequest)
+	}
+
+	saved, err := s.store.GetConnection(ctx, name)
+	if err != nil {
+		if errors.Is(err, ErrNotExists) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	saved.Datasource, err = s.Secret.Decrypt(saved.Datasource)
+	if err != nil {
+		return nil, fmt.Errorf("database %s: %w", name, err)
+	}
+
+	return saved, nil
+}
+
+// prepareConnection validates the connection and fills the datasource from the saved connection.
+func (s *Service) prepareConnection(ctx context.Context, conn *Connection) (*Connection, error) {
+	if err := conn.Validate(); err != nil {
+		return nil, err
+	}
+
+	saved, err := s.savedConnection(ctx, conn.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if conn.Datasource == "" {
+		if saved == nil {
+			return nil, fmt.Errorf("datasource is required; %w", ErrBadRequest)
+		}
+
+		conn.Datasource = saved.Datasource
+	}
+
+	return saved, nil
+}
+
+// SetConnection saves the connection and replaces the database at runtime.
+func (s *Service) SetConnection(ctx context.Context, conn *Connection) error {
+	unlock := s.connLocks.lock(conn.Name)
+	defer unlock()
+
+	saved, err := s.prepareConnection(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	if err := s.db.SetDatabase(ctx, conn); err != nil {
+		return err
+	}
+
+	encrypted := *conn
+	encrypted.Source = ""
+	encrypted.Datasource, err = s.Secret.Encrypt(conn.Datasource)
+	if err == nil {
+		err = s.store.SaveConnection(ctx, &encrypted)
+	}
+
+	if err != nil {
+		// runtime database follows the store
+		if saved != nil {
+			err = errors.Join(err, s.db.SetDatabase(ctx, saved))
+		} else {
+			err = errors.Join(err, s.db.RemoveDatabase(conn.Name))
+		}
+
+		return fmt.Errorf("save connection %s: %w", conn.Name, err)
+	}
+
+	s.Cache.Invalidate(CacheFilter{DBType: conn.Name})
+
+	return nil
+}
+
+// TestConnection connects to the database of the connection without saving it.
+func (s *Service) TestConnection(ctx context.Context, conn *Connection) error {
+	if _, err := s.prepareConnection(ctx, conn); err != nil {
+		return err
+	}
+
+	return s.db.TestDatabase(ctx, conn)
+}
+
+// DeleteConnection deletes the saved connection and removes its database.
+func (s *Service) DeleteConnection(ctx context.Context, name string) error {
+	unlock := s.connLocks.lock(name)
+	defer unlock()
+
+	isConfig, err := s.configDatabase(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	if isConfig {
+		return fmt.Errorf("database %s is in the config file, it can't be removed; %w", name, ErrBadRequest)
+	}
+
+	if err := s.store.DeleteConnection(ctx, name); err != nil {
+		return err
+	}
+
+	if err := s.db.RemoveDatabase(name); err != nil && !errors.Is(err, ErrNotExists) {
+		return err
+	}
+
+	s.Cache.Invalidate(CacheFilter{DBType: name})
+
+	return nil
+}
+
+// GetConnections returns the saved connections without datasources and the databases of the config file.
+func (s *Service) GetConnections(ctx context.Context) ([]Connection, error) {
+	saved, err := s.store.GetConnections(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	conns := make([]Connection, 0, len(saved))
+	names := make(map[string]struct{}, len(saved))
+	for _, conn := range saved {
+		conn.Datasource = ""
+		conn.Source = ConnectionStore
+
+		conns = append(conns, conn)
+		names[conn.Name] = struct{}{}
+	}
+
+	for _, db := range s.db.DatabaseList() {
+		if _, ok := names[db.Name]; ok {
+			continue
+		}
+
+		conns = append(conns, Connection{
+			Name:     db.Name,
+			DBType:   db.Type,
+			ReadOnly: db.ReadOnly,
+			Source:   ConnectionConfig,
+		})
+	}
+
+	slices.SortFunc(conns, func(a, b Connection) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return conns, nil
+}
+
+// LoadConnections adds the saved connections to the databases, databases of the config file have priority.
+func (s *Service) LoadConnections(ctx context.Context) error {
+	if s.store == nil {
+		return nil
+	}
+
+	saved, err := s.store.GetConnections(ctx)
+	if err != nil {
+		return fmt.Errorf("load connections: %w", err)
+	}
+
+	if len(saved) == 0 {
+		return nil
+	}
+
+	if s.Secret == nil {
+		logi.Ctx(ctx).Warn("saved connections are not loaded, secret key is not configured", slog.Int("count", len(saved)))
+
+		return nil
+	}
+
+	configNames := make(map[string]struct{})
+	for _, db := range s.db.DatabaseList() {
+		configNames[db.Name] = struct{}{}
+	}
+
+	var wg sync.WaitGroup
+	for _, conn := range saved {
+		if _, ok := configNames[conn.Name]; ok {
+			logi.Ctx(ctx).Warn("saved connection is skipped, config file has the database", slog.String("name", conn.Name))
+
+			continue
+		}
+
+		conn.Datasource, err = s.Secret.Decrypt(conn.Datasource)
+		if err != nil {
+			logi.Ctx(ctx).Error("saved connection is skipped", slog.String("name", conn.Name), slog.String("error", err.Error()))
+
+			continue
+		}
+
+		wg.Go(func() {
+			if err := s.db.SetDatabase(ctx, &conn); err != nil {
+				logi.Ctx(ctx).Error("saved connection is skipped", slog.String("name", conn.Name), slog.String("error", err.Error()))
+			}
+		})
+	}
+
+	wg.Wait()
+
+	return nil
+}
@@ -6,6 +6,7 @@ type ContextKey string
 
 const (
 	UserContextKey ContextKey = "USER"
+	NoteContextKey ContextKey = "NOTE"
 )
 
 func UserContext(ctx context.Context) string {
@@ -23,3 +24,20 @@ func ContextWithUser(ctx context.Context, user string) context.Context {
 
 	return context.WithValue(ctx, UserContextKey, user)
 }
+
+// NoteContext returns the path of the note running the cell.
+func NoteContext(ctx context.Context) string {
+	if note, ok := ctx.Value(NoteContextKey).(string); ok {
+		return note
+	}
+
+	return ""
+}
+
+func ContextWithNote(ctx context.Context, notePath string) context.Context {
+	if notePath == "" {
+		return ctx
+	}
+
+	return context.WithValue(ctx, NoteContextKey, notePath)
+}
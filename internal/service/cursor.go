@@ -0,0 +1,294 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"sync"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+)
+
+const (
+	DefaultCursorIdleTimeout = 5 * time.Minute
+	DefaultCursorMax         = 64
+)
+
+// PagedResult is the page of a paged query, NextPage is empty on the last page.
+type PagedResult interface {
+	Result
+	NextPage() string
+}
+
+// Cursors keeps open queries of paged cells until their last page is read or they are idle.
+type Cursors struct {
+	// IdleTimeout closes a cursor not fetched in this duration.
+	IdleTimeout time.Duration
+	// Max is the number of open cursors, new paged queries fail above it.
+	Max int
+
+	mu      sync.Mutex
+	cursors map[string]*cursor
+}
+
+type cursor struct {
+	mu sync.Mutex
+
+	columns     []string
+	columnTypes []ColumnInfo
+	pageSize    int64
+	next        func() ([]any, error, bool)
+	stop        func()
+	cancel      context.CancelFunc
+	timer       *time.Timer
+	expires     time.Time
+	// peeked is the first row of the next page.
+	peeked []any
+	// err is the failure of reading after the rows of the last page, the next page returns it.
+	err    error
+	closed bool
+}
+
+type pageResult struct {
+	columns     []string
+	columnTypes []ColumnInfo
+	rows        [][]any
+	duration    time.Duration
+	nextPage    string
+}
+
+func (r *pageResult) Columns() []string         { return r.columns }
+func (r *pageResult) ColumnTypes() []ColumnInfo { return r.columnTypes }
+func (r *pageResult) Rows() [][]any             { return r.rows }
+func (r *pageResult) RowsAffected() int64       { return 0 }
+func (r *pageResult) Duration() time.Duration   { return r.duration }
+func (r *pageResult) NextPage() string          { return r.nextPage }
+
+func (c *Cursors) idleTimeout() time.Duration {
+	if c.IdleTimeout <= 0 {
+		return DefaultCursorIdleTimeout
+	}
+
+	return c.IdleTimeout
+}
+
+func (c *Cursors) max() int {
+	if c.Max <= 0 {
+		return DefaultCursorMax
+	}
+
+	return c.Max
+}
+
+// Open reads the first page of the stream, the stream stays open with a token when more rows exist.
+// The query of the stream must not be canceled with the request, cancel stops it when the cursor is closed.
+func (c *Cursors) Open(stream *RowStream, cancel context.CancelFunc, pageSize int64) (Result, error) {
+	c.mu.Lock()
+	if len(c.cursors) >= c.max() {
+		c.mu.Unlock()
+
+		// rows are closed by the iteration
+		for range stream.Rows {
+			break
+		}
+
+		cancel()
+
+		return nil, fmt.Errorf("too many open cursors, read or close pages of other queries; %w", ErrBadRequest)
+	}
+
+	next, stop := iter.Pull2(stream.Rows)
+
+	cur := &cursor{
+		columns:     stream.Columns,
+		columnTypes: stream.ColumnTypes,
+		pageSize:    pageSize,
+		next:        next,
+		stop:        stop,
+		cancel:      cancel,
+	}
+
+	token := ulid.Make().String()
+	if c.cursors == nil {
+		c.cursors = make(map[string]*cursor)
+	}
+
+	c.cursors[token] = cur
+	c.mu.Unlock()
+
+	cur.mu.Lock()
+	defer cur.mu.Unlock()
+
+	return c.page(token, cur)
+}
+
+// Next reads the next page of the cursor.
+func (c *Cursors) Next(token string) (Result, error) {
+	c.mu.Lock()
+	cur, ok := c.cursors[token]
+	c.mu.Unlock()
+
+	if !ok {
+		return nil, fmt.Errorf("page %s is expired or read; %w", token, ErrNotExists)
+	}
+
+	cur.mu.Lock()
+	defer cur.mu.Unlock()
+
+	if cur.closed {
+		return nil, fmt.Errorf("page %s is expired or read; %w", token, ErrNotExists)
+	}
+
+	return c.page(token, cur)
+}
+
+// Close closes the cursor before its last page.
+func (c *Cursors) Close(token string) error {
+	c.mu.Lock()
+	cur, ok := c.cursors[token]
+	delete(c.cursors, token)
+	c.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("page %s is expired or read; %w", token, ErrNotExists)
+	}
+
+	cur.mu.Lock()
+	defer cur.mu.Unlock()
+
+	cur.close()
+
+	return nil
+}
+
+// page reads a page of the locked cursor and one more row to know a next page exists.
+func (c *Cursors) page(token string, cur *cursor) (Result, error) {
+	start := time.Now()
+
+	if cur.err != nil {
+		c.remove(token, cur)
+
+		return nil, cur.err
+	}
+
+	rows := make([][]any, 0, cur.pageSize)
+	if cur.peeked != nil {
+		rows = append(rows, cur.peeked)
+		cur.peeked = nil
+	}
+
+	for int64(len(rows)) <= cur.pageSize {
+		row, err, ok := cur.next()
+		if err != nil {
+			if len(rows) == 0 {
+				c.remove(token, cur)
+
+				return nil, err
+			}
+
+			// rows read before are not lost
+			cur.err = err
+
+			break
+		}
+
+		if !ok {
+			break
+		}
+
+		if int64(len(rows)) == cur.pageSize {
+			cur.peeked = row
+
+			break
+		}
+
+		rows = append(rows, row)
+	}
+
+	result := &pageResult{
+		columns:     cur.columns,
+		columnTypes: cur.columnTypes,
+		rows:        rows,
+		duration:    time.Since(start),
+	}
+
+	if cur.peeked == nil && cur.err == nil {
+		c.remove(token, cur)
+
+		return result, nil
+	}
+
+	result.nextPage = token
+	cur.expires = time.Now().Add(c.idleTimeout())
+
+	if cur.timer == nil {
+		cur.timer = time.AfterFunc(c.idleTimeout(), func() {
+			cur.mu.Lock()
+			defer cur.mu.Unlock()
+
+			// fetched while the timer was waiting for the lock
+			if time.Now().Before(cur.expires) {
+				return
+			}
+
+			c.remove(token, cur)
+		})
+	} else {
+		cur.timer.Reset(c.idleTimeout())
+	}
+
+	return result, nil
+}
+
+// remove closes the locked cursor and forgets its token.
+func (c *Cursors) remove(token string, cur *cursor) {
+	c.mu.Lock()
+	if c.cursors[token] == cur {
+		delete(c.cursors, token)
+	}
+	c.mu.Unlock()
+
+	cur.close()
+}
+
+func (cur *cursor) close() {
+	if cur.closed {
+		return
+	}
+
+	cur.closed = true
+	if cur.timer != nil {
+		cur.timer.Stop()
+	}
+
+	cur.stop()
+	cur.cancel()
+}
+
+// openPage runs the query of the cell in a cursor and returns its first page.
+// The first page is read under the timeout of ctx, the cursor outlives it after.
+func (s *Service) openPage(ctx context.Context, cell *Cell, content string, cellParams map[string]any) (Result, error) {
+	ctxCursor, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stopTimeout := context.AfterFunc(ctx, cancel)
+	defer stopTimeout()
+
+	stream, err := s.db.QueryStream(ctxCursor, cell.DBType, content, cellParams, cell.Limit, cell.Typed.V, cell.Snapshot)
+	if err != nil {
+		cancel()
+
+		return nil, err
+	}
+
+	return s.Cursors.Open(stream, cancel, cell.PageSize)
+}
+
+// NextPage returns the next page of a paged query.
+func (s *Service) NextPage(_ context.Context, token string) (Result, error) {
+	return s.Cursors.Next(token)
+}
+
+// ClosePage closes a paged query before its last page.
+func (s *Service) ClosePage(_ context.Context, token string) error {
+	return s.Cursors.Close(token)
+}
@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/worldline-go/types"
+)
+
+// countStream returns a stream of n rows, closed reports when the rows are closed.
+func countStream(n int, closed *bool) *RowStream {
+	return &RowStream{
+		Columns: []string{"n"},
+		Rows: func(yield func([]any, error) bool) {
+			defer func() { *closed = true }()
+
+			for i := range n {
+				if !yield([]any{i}, nil) {
+					return
+				}
+			}
+		},
+	}
+}
+
+func pageValues(t *testing.T, result Result) []any {
+	t.Helper()
+
+	var values []any
+	for _, row := range result.Rows() {
+		values = append(values, row[0])
+	}
+
+	return values
+}
+
+func TestCursors(t *testing.T) {
+	t.Run("pages", func(t *testing.T) {
+		c := &Cursors{}
+
+		var closed, canceled bool
+		result, err := c.Open(countStream(5, &closed), func() { canceled = true }, 2)
+		require.NoError(t, err)
+		require.Equal(t, []any{0, 1}, pageValues(t, result))
+
+		token := result.(PagedResult).NextPage()
+		require.NotEmpty(t, token)
+
+		result, err = c.Next(token)
+		require.NoError(t, err)
+		require.Equal(t, []any{2, 3}, pageValues(t, result))
+		require.Equal(t, token, result.(PagedResult).NextPage())
+
+		result, err = c.Next(token)
+		require.NoError(t, err)
+		require.Equal(t, []any{4}, pageValues(t, result))
+		require.Empty(t, result.(PagedResult).NextPage())
+		require.True(t, closed)
+		require.True(t, canceled)
+
+		_, err = c.Next(token)
+		require.ErrorIs(t, err, ErrNotExists)
+	})
+
+	t.Run("exact last page", func(t *testing.T) {
+		c := &Cursors{}
+
+		var closed bool
+		result, err := c.Open(countStream(2, &closed), func() {}, 2)
+		require.NoError(t, err)
+		require.Equal(t, []any{0, 1}, pageValues(t, result))
+		require.Empty(t, result.(PagedResult).NextPage())
+		require.True(t, closed)
+	})
+
+	t.Run("close and max", func(t *testing.T) {
+		c := &Cursors{Max: 1}
+
+		var closed bool
+		result, err := c.Open(countStream(5, &closed), func() {}, 1)
+		require.NoError(t, err)
+
+		var closedOther bool
+		_, err = c.Open(countStream(5, &closedOther), func() {}, 1)
+		require.ErrorIs(t, err, ErrBadRequest)
+		require.True(t, closedOther)
+
+		require.NoError(t, c.Close(result.(PagedResult).NextPage()))
+		require.True(t, closed)
+		require.ErrorIs(t, c.Close(result.(PagedResult).NextPage()), ErrNotExists)
+	})
+
+	t.Run("idle expire", func(t *testing.T) {
+		c := &Cursors{IdleTimeout: 10 * time.Millisecond}
+
+		var closed bool
+		result, err := c.Open(countStream(5, &closed), func() {}, 1)
+		require.NoError(t, err)
+
+		token := result.(PagedResult).NextPage()
+		require.Eventually(t, func() bool {
+			_, err := c.Next(token)
+
+			return errors.Is(err, ErrNotExists)
+		}, time.Second, 20*time.Millisecond)
+	})
+
+	t.Run("query error", func(t *testing.T) {
+		c := &Cursors{}
+
+		stream := &RowStream{
+			Columns: []string{"n"},
+			Rows: func(yield func([]any, error) bool) {
+				if !yield([]any{0}, nil) {
+					return
+				}
+
+				yield(nil, context.DeadlineExceeded)
+			},
+		}
+
+		// rows before the error are returned, the next page fails
+		result, err := c.Open(stream, func() {}, 1)
+		require.NoError(t, err)
+		require.Equal(t, []any{0}, pageValues(t, result))
+
+		_, err = c.Next(result.(PagedResult).NextPage())
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.Empty(t, c.cursors)
+
+		// error without rows fails the page
+		stream.Rows = func(yield func([]any, error) bool) {
+			yield(nil, context.DeadlineExceeded)
+		}
+
+		_, err = c.Open(stream, func() {}, 1)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.Empty(t, c.cursors)
+	})
+}
+
+// slowStreamDatabase streams rows after delay or fails when the context is done first.
+type slowStreamDatabase struct {
+	Database
+
+	delay time.Duration
+}
+
+func (d *slowStreamDatabase) QueryTimeout(string) time.Duration {
+	return 0
+}
+
+func (d *slowStreamDatabase) QueryStream(ctx context.Context, _, _ string, _ map[string]any, _ int64, _ bool, _ Snapshot) (*RowStream, error) {
+	return &RowStream{
+		Columns: []string{"n"},
+		Rows: func(yield func([]any, error) bool) {
+			for i := range 3 {
+				select {
+				case <-ctx.Done():
+					yield(nil, errors.New("driver: query canceled"))
+
+					return
+				case <-time.After(d.delay):
+				}
+
+				if !yield([]any{i}, nil) {
+					return
+				}
+			}
+		},
+	}, nil
+}
+
+func TestOpenPageTimeout(t *testing.T) {
+	s := New(&slowStreamDatabase{delay: time.Second}, nil)
+
+	// first page is read under the cell timeout
+	_, err := s.Run(t.Context(), &Cell{DBType: "db", Content: "SELECT n", Result: types.NewNull(true), PageSize: 1, Timeout: "20ms"}, nil, nil)
+	require.ErrorIs(t, err, ErrTimeout)
+	require.Empty(t, s.Cursors.cursors)
+
+	// cursor outlives the timeout after the first page
+	s = New(&slowStreamDatabase{delay: 10 * time.Millisecond}, nil)
+
+	result, err := s.Run(t.Context(), &Cell{DBType: "db", Content: "SELECT n", Result: types.NewNull(true), PageSize: 1, Timeout: "200ms"}, nil, nil)
+	require.NoError(t, err)
+
+	time.Sleep(250 * time.Millisecond)
+
+	result, err = s.NextPage(t.Context(), result.(PagedResult).NextPage())
+	require.NoError(t, err)
+	require.Equal(t, []any{1}, pageValues(t, result))
+}
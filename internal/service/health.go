@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	HealthUp   = "up"
+	HealthDown = "down"
+
+	DefaultHealthTimeout = 5 * time.Second
+)
+
+// Health is the state of the components of the service.
+type Health struct {
+	// Status is down when a required component is down.
+	Status     string            `json:"status"`
+	Components []ComponentHealth `json:"components,omitempty"`
+}
+
+// ComponentHealth is the ping result of the store or a database.
+type ComponentHealth struct {
+	Name string `json:"name"`
+	// Kind is store or database.
+	Kind       string  `json:"kind"`
+	Status     string  `json:"status"`
+	Required   bool    `json:"required"`
+	Error      string  `json:"error,omitempty"`
+	DurationMS float64 `json:"duration_ms"`
+}
+
+// Health pings the store and the databases at the same time with HealthTimeout.
+func (s *Service) Health(ctx context.Context) Health {
+	timeout := s.HealthTimeout
+	if timeout <= 0 {
+		timeout = DefaultHealthTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	databases := s.db.DatabaseList()
+
+	components := make([]ComponentHealth, 0, len(databases)+1)
+	pings := make([]func(context.Context) error, 0, len(databases)+1)
+
+	if s.store != nil {
+		components = append(components, ComponentHealth{Name: "store", Kind: "store", Required: true})
+		pings = append(pings, s.store.Ping)
+	}
+
+	for _, db := range databases {
+		components = append(components, ComponentHealth{Name: db.Name, Kind: "database", Required: db.Required})
+		pings = append(pings, func(ctx context.Context) error {
+			return s.db.Ping(ctx, db.Name)
+		})
+	}
+
+	var wg sync.WaitGroup
+	for i := range components {
+		wg.Go(func() {
+			start := time.Now()
+			err := pings[i](ctx)
+
+			components[i].DurationMS = float64(time.Since(start).Microseconds()) / 1000
+			components[i].Status = HealthUp
+			if err != nil {
+				components[i].Status = HealthDown
+				components[i].Error = err.Error()
+			}
+		})
+	}
+
+	wg.Wait()
+
+	health := Health{Status: HealthUp, Components: components}
+	for _, component := range components {
+		if component.Required && component.Status == HealthDown {
+			health.Status = HealthDown
+		}
+	}
+
+	return health
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type pingStore struct {
+	Storer
+
+	err error
+}
+
+func (s *pingStore) Ping(context.Context) error {
+	return s.err
+}
+
+// pingDatabase fails pings of the databases in down.
+type pingDatabase struct {
+	Database
+
+	list []DatabaseStatus
+	down map[string]bool
+}
+
+func (d *pingDatabase) DatabaseList() []DatabaseStatus {
+	return d.list
+}
+
+func (d *pingDatabase) Ping(ctx context.Context, name string) error {
+	if d.down[name] {
+		return errors.New("connection refused")
+	}
+
+	return ctx.Err()
+}
+
+func TestHealth(t *testing.T) {
+	db := &pingDatabase{
+		list: []DatabaseStatus{{Name: "main", Required: true}, {Name: "reporting"}},
+		down: map[string]bool{},
+	}
+	store := &pingStore{}
+	s := New(db, store)
+
+	health := s.Health(t.Context())
+	require.Equal(t, HealthUp, health.Status)
+	require.Len(t, health.Components, 3)
+	require.Equal(t, ComponentHealth{Name: "store", Kind: "store", Status: HealthUp, Required: true}, withoutDuration(health.Components[0]))
+
+	// optional database doesn't change the status
+	db.down["reporting"] = true
+
+	health = s.Health(t.Context())
+	require.Equal(t, HealthUp, health.Status)
+	require.Equal(t, ComponentHealth{Name: "reporting", Kind: "database", Status: HealthDown, Error: "connection refused"}, withoutDuration(health.Components[2]))
+
+	db.down["main"] = true
+	require.Equal(t, HealthDown, s.Health(t.Context()).Status)
+
+	db.down = map[string]bool{}
+	store.err = errors.New("store is down")
+	require.Equal(t, HealthDown, s.Health(t.Context()).Status)
+}
+
+func withoutDuration(c ComponentHealth) ComponentHealth {
+	c.DurationMS = 0
+
+	return c
+}
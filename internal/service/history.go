@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/rakunlabs/logi"
+	"github.com/worldline-go/types"
+)
+
+const (
+	HistorySuccess = "success"
+	HistoryError   = "error"
+	HistoryTimeout = "timeout"
+
+	DefaultHistoryLimit = 100
+	MaxHistoryLimit     = 1000
+
+	historyCleanInterval = time.Hour
+)
+
+// History is a record of a cell run.
+type History struct {
+	ID   string `json:"id"`
+	User string `json:"user,omitempty"`
+	// DBType is the database name of the cell.
+	DBType string `json:"db_type"`
+	// Note and Cell are the paths of note runs.
+	Note string `json:"note,omitempty"`
+	Cell string `json:"cell,omitempty"`
+	// Kind is query, exec, stream, explain, script or transfer.
+	Kind string `json:"kind"`
+	// Query is the rendered content of the cell.
+	Query        string                 `json:"query"`
+	Params       map[string]any         `json:"params,omitempty"`
+	DurationMS   float64                `json:"duration_ms"`
+	Rows         int64                  `json:"rows"`
+	RowsAffected int64                  `json:"rows_affected"`
+	Status       string                 `json:"status"`
+	Error        string                 `json:"error,omitempty"`
+	CreatedAt    types.Null[types.Time] `json:"created_at"`
+}
+
+// HistoryFilter selects history records newest first, empty fields match all.
+type HistoryFilter struct {
+	User   string
+	DBType string
+	Note   string
+	From   time.Time
+	To     time.Time
+	Limit  int
+	Offset int
+}
+
+// historyKind returns the kind of run of the cell.
+func historyKind(cell *Cell) string {
+	switch {
+	case cell.Explain.V:
+		return "explain"
+	case cell.Mode.V.Enabled:
+		return cell.Mode.V.Name
+	case cell.Result.V:
+		return "query"
+	default:
+		return "exec"
+	}
+}
+
+// record saves the run of the cell to the history, failures are only logged.
+func (s *Service) record(ctx context.Context, cell *Cell, history *History, start time.Time, err error) {
+	if s.store == nil || s.HistoryDisabled {
+		return
+	}
+
+	history.ID = ulid.Make().String()
+	history.User = UserContext(ctx)
+	history.Note = NoteContext(ctx)
+	history.DBType = cell.DBType
+	history.Cell = cell.Path.V
+	history.DurationMS = float64(time.Since(start).Microseconds()) / 1000
+	history.Status = HistorySuccess
+
+	if err != nil {
+		history.Status = HistoryError
+		if errors.Is(err, ErrTimeout) {
+			history.Status = HistoryTimeout
+		}
+
+		history.Error = err.Error()
+	}
+
+	// run can be canceled, the record is still saved
+	if err := s.store.AddHistory(context.WithoutCancel(ctx), history); err != nil {
+		logi.Ctx(ctx).Error("failed to save history", slog.String("error", err.Error()))
+	}
+}
+
+// GetHistory returns the records of cell runs matching the filter.
+func (s *Service) GetHistory(ctx context.Context, filter HistoryFilter) ([]History, error) {
+	if filter.Limit < 0 || filter.Offset < 0 {
+		return nil, fmt.Errorf("limit and offset can't be negative; %w", ErrBadRequest)
+	}
+
+	if filter.Limit == 0 {
+		filter.Limit = DefaultHistoryLimit
+	}
+
+	filter.Limit = min(filter.Limit, MaxHistoryLimit)
+
+	if !filter.From.IsZero() && !filter.To.IsZero() && filter.To.Before(filter.From) {
+		return nil, fmt.Errorf("to is before from; %w", ErrBadRequest)
+	}
+
+	return s.store.GetHistory(ctx, filter)
+}
+
+// CleanHistory deletes records older than HistoryRetention every hour until ctx is done.
+func (s *Service) CleanHistory(ctx context.Context) {
+	if s.store == nil || s.HistoryRetention <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(historyCleanInterval)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := s.store.DeleteHistory(ctx, time.Now().Add(-s.HistoryRetention))
+		if err != nil {
+			logi.Ctx(ctx).Error("failed to clean history", slog.String("error", err.Error()))
+		} else if deleted > 0 {
+			logi.Ctx(ctx).Info("cleaned history", slog.Int64("deleted", deleted))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
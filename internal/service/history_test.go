@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// historyStore keeps added records, other store calls are not used.
+type historyStore struct {
+	Storer
+
+	histories []*History
+	filter    HistoryFilter
+}
+
+func (s *historyStore) AddHistory(_ context.Context, history *History) error {
+	s.histories = append(s.histories, history)
+
+	return nil
+}
+
+func (s *historyStore) GetHistory(_ context.Context, filter HistoryFilter) ([]History, error) {
+	s.filter = filter
+
+	return nil, nil
+}
+
+// historyDatabase returns one row for queries and fails exec.
+type historyDatabase struct {
+	Database
+}
+
+func (d *historyDatabase) QueryTimeout(string) time.Duration {
+	return 0
+}
+
+func (d *historyDatabase) Query(_ context.Context, _, _ string, _ map[string]any, _ int64, _ bool, _ Snapshot) (Result, error) {
+	return &TransferResult{columns: []string{"n"}, rows: [][]any{{"1"}}}, nil
+}
+
+func (d *historyDatabase) Exec(context.Context, string, string, map[string]any) (Result, error) {
+	return nil, errors.New("permission denied")
+}
+
+func (d *historyDatabase) QueryStream(_ context.Context, _, _ string, _ map[string]any, _ int64, _ bool, _ Snapshot) (*RowStream, error) {
+	var closed bool
+
+	return countStream(3, &closed), nil
+}
+
+func TestHistory(t *testing.T) {
+	store := &historyStore{}
+	s := New(&historyDatabase{}, store)
+
+	ctx := ContextWithNote(ContextWithUser(t.Context(), "alice"), "reports")
+
+	cell := &Cell{DBType: "replica", Content: "SELECT * FROM users WHERE id = :id", Params: []Param{{Name: "id", Path: "id"}}}
+	cell.Result.V = true
+	cell.Path.V = "users"
+
+	_, err := s.Run(ctx, cell, map[string]any{"id": 7}, nil)
+	require.NoError(t, err)
+
+	_, err = s.Run(t.Context(), &Cell{DBType: "replica", Content: "DELETE FROM users"}, map[string]any{}, nil)
+	require.Error(t, err)
+
+	stream, err := s.RunStream(t.Context(), cell, map[string]any{"id": 7})
+	require.NoError(t, err)
+
+	for range stream.Rows {
+	}
+
+	require.Len(t, store.histories, 3)
+
+	query := store.histories[0]
+	require.NotEmpty(t, query.ID)
+	require.Equal(t, "alice", query.User)
+	require.Equal(t, "reports", query.Note)
+	require.Equal(t, "users", query.Cell)
+	require.Equal(t, "replica", query.DBType)
+	require.Equal(t, "query", query.Kind)
+	require.Equal(t, "SELECT * FROM users WHERE id = :id", query.Query)
+	require.Equal(t, map[string]any{"id": 7}, query.Params)
+	require.EqualValues(t, 1, query.Rows)
+	require.Equal(t, HistorySuccess, query.Status)
+
+	exec := store.histories[1]
+	require.Equal(t, "exec", exec.Kind)
+	require.Equal(t, HistoryError, exec.Status)
+	require.Contains(t, exec.Error, "permission denied")
+
+	streamed := store.histories[2]
+	require.Equal(t, "stream", streamed.Kind)
+	require.EqualValues(t, 3, streamed.Rows)
+
+	s.HistoryDisabled = true
+	_, err = s.Run(ctx, cell, map[string]any{"id": 7}, nil)
+	require.NoError(t, err)
+	require.Len(t, store.histories, 3)
+}
+
+func TestGetHistory(t *testing.T) {
+	store := &historyStore{}
+	s := New(&historyDatabase{}, store)
+
+	_, err := s.GetHistory(t.Context(), HistoryFilter{})
+	require.NoError(t, err)
+	require.Equal(t, DefaultHistoryLimit, store.filter.Limit)
+
+	_, err = s.GetHistory(t.Context(), HistoryFilter{Limit: 5000})
+	require.NoError(t, err)
+	require.Equal(t, MaxHistoryLimit, store.filter.Limit)
+
+	now := time.Now()
+	_, err = s.GetHistory(t.Context(), HistoryFilter{From: now, To: now.Add(-time.Hour)})
+	require.ErrorIs(t, err, ErrBadRequest)
+
+	_, err = s.GetHistory(t.Context(), HistoryFilter{Offset: -1})
+	require.ErrorIs(t, err, ErrBadRequest)
+}
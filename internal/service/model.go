@@ -12,6 +12,12 @@ import (
 var (
 	ErrNotExists  = errors.New("not exist")
 	ErrBadRequest = errors.New("bad request")
+	// ErrTimeout is returned when a cell runs longer than its timeout.
+	ErrTimeout = errors.New("timeout")
+	// ErrForbidden is returned for writes on read only databases.
+	ErrForbidden = errors.New("forbidden")
+	// ErrUnavailable is returned when the database is not connected.
+	ErrUnavailable = errors.New("unavailable")
 )
 
 type Note struct {
@@ -46,8 +52,59 @@ type Cell struct {
 	Template    Template               `json:"template"`
 	Path        types.Null[string]     `json:"path,omitzero"`
 	Dependency  types.Null[Dependency] `json:"dependency,omitzero"`
+	Snapshot    Snapshot               `json:"snapshot,omitzero"`
+	// Typed keeps native types of query results and returns column types.
+	Typed types.Null[bool] `json:"typed,omitzero"`
+	// Params are bound to :name placeholders of the content through the driver.
+	Params []Param `json:"params,omitempty"`
+	// PageSize returns the result in pages of this size, next pages are read with the page token.
+	PageSize int64 `json:"page_size,omitempty"`
+	// Timeout of the cell run like 30s or 5m, default is the query timeout of the database.
+	Timeout string `json:"timeout,omitempty"`
+	// Explain returns the plan of the query instead of its result, transfer cells explain the source query.
+	Explain types.Null[bool] `json:"explain,omitzero"`
+	// Cache keeps the result of the query for the same rendered content and params.
+	Cache Cache `json:"cache,omitzero"`
 }
 
+// Cache of the query result of a cell, snapshot reads and paged queries are not cached.
+type Cache struct {
+	Enabled bool `json:"enabled"`
+	// TTL like 30s or 5m, default is the ttl of the cache config.
+	TTL string `json:"ttl,omitempty"`
+}
+
+// Param is a named parameter of the cell content, value is read from the cell values.
+type Param struct {
+	Name string `json:"name"`
+	// Path of the value in cell values like data.status or cells.users.0.id.
+	Path string `json:"path"`
+	// Type converts the value: string, integer, number, boolean or empty to keep it.
+	Type string `json:"type,omitempty"`
+	// Default is used when the path does not exist, NULL when not set.
+	Default any `json:"default,omitempty"`
+	// Required fails the cell when the path does not exist.
+	Required bool `json:"required,omitempty"`
+}
+
+// Snapshot reads the query of a query or transfer cell in a read only transaction.
+// Cells of a note run share the transaction of the database, so the whole note reads the same state.
+type Snapshot struct {
+	Enabled bool `json:"enabled"`
+	// Isolation of the transaction, repeatable_read (default), serializable or snapshot (SQL Server).
+	Isolation string `json:"isolation,omitempty"`
+	// ID imports an exported PostgreSQL snapshot like 00000003-0000001B-1.
+	ID string `json:"id,omitempty"`
+	// SCN reads Oracle as of this system change number with flashback.
+	SCN string `json:"scn,omitempty"`
+}
+
+const (
+	IsolationRepeatableRead = "repeatable_read"
+	IsolationSerializable   = "serializable"
+	IsolationSnapshot       = "snapshot"
+)
+
 type Dependency struct {
 	Enabled bool     `json:"enabled"`
 	Names   []string `json:"names"`
@@ -58,14 +115,32 @@ type Template struct {
 }
 
 type Mode struct {
-	Enabled   bool      `json:"enabled"`
-	Name      string    `json:"name"`
-	DBType    string    `json:"db_type"`
-	Table     string    `json:"table"`
-	Wipe      bool      `json:"wipe"`
-	SkipError SkipError `json:"skip_error"`
-	MapType   MapType   `json:"map_type"`
-	Batch     int       `json:"batch"`
+	Enabled bool `json:"enabled"`
+	// Name of the mode.
+	//  - transfer: copy the query result to tables
+	//  - script: split the content to statements and run them in order
+	Name         string        `json:"name"`
+	DBType       string        `json:"db_type"`
+	Table        string        `json:"table"`
+	Wipe         bool          `json:"wipe"`
+	SkipError    SkipError     `json:"skip_error"`
+	MapType      MapType       `json:"map_type"`
+	LOB          LOB           `json:"lob"`
+	Batch        int           `json:"batch"`
+	Destinations []Destination `json:"destinations,omitempty"`
+	// OnError is the policy for fan-out transfers when a destination fails.
+	//  - abort: first failure stops the remaining destinations, committed ones are not rolled back (default)
+	//  - continue: keep writing to the remaining destinations
+	OnError string `json:"on_error,omitempty"`
+}
+
+// Destination is an additional target of a transfer, the source query runs once
+// and its rows are written to every destination concurrently.
+type Destination struct {
+	DBType  string  `json:"db_type"`
+	Table   string  `json:"table"`
+	Wipe    bool    `json:"wipe"`
+	MapType MapType `json:"map_type"`
 }
 
 type SkipError struct {
@@ -73,6 +148,15 @@ type SkipError struct {
 	Message string `json:"message"`
 }
 
+// LOB configures handling of large object columns (BLOB, CLOB, bytea) in transfers.
+type LOB struct {
+	Enabled bool `json:"enabled"`
+	// MaxSize is the maximum size of a value in bytes, 0 is unlimited.
+	MaxSize int64 `json:"max_size"`
+	// Truncate cuts values bigger than MaxSize instead of failing the transfer.
+	Truncate bool `json:"truncate"`
+}
+
 type MapType struct {
 	Enabled     bool                          `json:"enabled"`
 	Column      map[string]ColumnType         `json:"column"`
@@ -89,11 +173,86 @@ type ColumnTypeTemplate struct {
 	Nullable bool        `json:"nullable"`
 	Template EnableValue `json:"template"`
 	Encoding Encoding    `json:"encoding"`
+	// Format of the destination value.
+	//  - bytes: base64, hex to decode string values
+	//  - uuid: binary, binary_mixed (MSSQL byte order) to write 16 bytes instead of string
+	Format string `json:"format,omitempty"`
+	// Date options of the date type.
+	Date Date `json:"date,omitzero"`
+	// Null policy applied to the source value before conversion.
+	Null NullPolicy `json:"null,omitzero"`
+	// Mask anonymises the value, applied after the null policy.
+	Mask Mask `json:"mask,omitzero"`
+}
+
+// Mask replaces personal data with deterministic values.
+// Hash and fake values use the salt of the mask config, same value always gives the same result.
+type Mask struct {
+	// Type of the mask.
+	//  - hash: hex HMAC-SHA256 of the value
+	//  - redact: replace letters and digits with *, separators are kept
+	//  - null: write NULL
+	//  - name, email, iban: fake value derived from the value
+	//  - date_shift: move the date up to Days days, only for date type
+	Type string `json:"type"`
+	// Length truncates the hash, full hash has 64 characters.
+	Length int `json:"length,omitempty"`
+	// Keep is the number of trailing characters not redacted.
+	Keep int `json:"keep,omitempty"`
+	// Days is the maximum shift of date_shift in both directions.
+	Days int `json:"days,omitempty"`
+}
+
+const (
+	MaskHash      = "hash"
+	MaskRedact    = "redact"
+	MaskNull      = "null"
+	MaskName      = "name"
+	MaskEmail     = "email"
+	MaskIBAN      = "iban"
+	MaskDateShift = "date_shift"
+)
+
+// NullPolicy normalises NULL and empty source values of a column.
+type NullPolicy struct {
+	// EmptyToNull treats empty and whitespace only text values as NULL.
+	EmptyToNull bool `json:"empty_to_null,omitempty"`
+	// OnNull is applied to NULL values, NULL is kept when empty.
+	//  - default: replace with Default, converted to the column type
+	//  - empty: replace with empty string, only for string and bytes types
+	//  - fail: stop the transfer with an error
+	OnNull string `json:"on_null,omitempty"`
+	// Default is the value used by the default policy.
+	Default string `json:"default,omitempty"`
+}
+
+const (
+	NullDefault = "default"
+	NullEmpty   = "empty"
+	NullFail    = "fail"
+)
+
+// Date makes date conversion deterministic across databases.
+// Layouts are Go reference layouts (2006-01-02 15:04:05) or names like RFC3339, DateTime, DateOnly.
+type Date struct {
+	// InputLayout parses text values, layouts are guessed when empty.
+	InputLayout string `json:"input_layout,omitempty"`
+	// OutputLayout writes the date as text instead of a time value.
+	OutputLayout string `json:"output_layout,omitempty"`
+	// SourceZone is the IANA time zone of source values without zone, like Oracle DATE.
+	SourceZone string `json:"source_zone,omitempty"`
+	// DestinationZone converts the value to this IANA time zone before writing.
+	DestinationZone string `json:"destination_zone,omitempty"`
+	// DateOnly truncates the value to midnight.
+	DateOnly bool `json:"date_only,omitempty"`
 }
 
 type Encoding struct {
-	Enabled bool   `json:"enabled"`
-	Coding  string `json:"coding"`
+	Enabled bool `json:"enabled"`
+	// Coding is the name of the source encoding like "ISO 8859-1", "windows-1252", "IBM037", "UTF-16" or "auto".
+	Coding string `json:"coding"`
+	// Fallback is the encoding used by "auto" when data is not UTF-8 or UTF-16, default is windows-1252.
+	Fallback string `json:"fallback,omitempty"`
 }
 
 type EnableValue struct {
@@ -104,28 +263,163 @@ type EnableValue struct {
 // /////////////////////////////////////////////
 
 type Storer interface {
+	Ping(ctx context.Context) error
+
 	Get(ctx context.Context, id string) (*Note, error)
 	GetWithPath(ctx context.Context, path string) (*Note, error)
 	GetNotes(ctx context.Context) ([]IDName, error)
 	Save(ctx context.Context, note *Note) error
 	Delete(ctx context.Context, id string) error
+
+	AddHistory(ctx context.Context, history *History) error
+	GetHistory(ctx context.Context, filter HistoryFilter) ([]History, error)
+	// DeleteHistory deletes records created before the time and returns their count.
+	DeleteHistory(ctx context.Context, before time.Time) (int64, error)
+
+	// Connections are saved with encrypted datasources.
+	GetConnections(ctx context.Context) ([]Connection, error)
+	GetConnection(ctx context.Context, name string) (*Connection, error)
+	SaveConnection(ctx context.Context, conn *Connection) error
+	DeleteConnection(ctx context.Context, name string) error
 }
 
 // /////////////////////////////////////////////
 
+// Kinds of values in the Type of ColumnInfo.
+const (
+	KindInteger = "integer"
+	KindNumber  = "number"
+	KindDecimal = "decimal"
+	KindBoolean = "boolean"
+	KindString  = "string"
+	KindDate    = "date"
+	KindBytes   = "bytes"
+	KindJSON    = "json"
+	KindUUID    = "uuid"
+)
+
+// ColumnInfo describes a column of a typed query result.
+type ColumnInfo struct {
+	Name string `json:"name"`
+	// Type is the kind of values: integer, number, decimal, boolean, string, date, bytes, json or uuid.
+	// Decimals are strings, dates are RFC3339 and bytes are base64 in JSON.
+	Type string `json:"type"`
+	// DatabaseType is the type name reported by the driver.
+	DatabaseType string           `json:"database_type"`
+	Nullable     types.Null[bool] `json:"nullable,omitzero"`
+}
+
 type Result interface {
 	Columns() []string
+	// ColumnTypes returns column metadata of typed results, nil otherwise.
+	ColumnTypes() []ColumnInfo
 	Rows() [][]any
 	RowsAffected() int64
 	Duration() time.Duration
 }
 
+// PlanNode is a step of a query plan in the same shape for all databases.
+type PlanNode struct {
+	Operation string `json:"operation"`
+	// Object is the table or index of the step.
+	Object string `json:"object,omitempty"`
+	// Detail has extra information of the step like conditions.
+	Detail string `json:"detail,omitempty"`
+	// Cost is the estimated cost in units of the database.
+	Cost types.Null[float64] `json:"cost,omitzero"`
+	// Rows is the estimated row count.
+	Rows types.Null[float64] `json:"rows,omitzero"`
+	// ActualRows and ActualTime in milliseconds are set when the query is analyzed.
+	ActualRows types.Null[float64] `json:"actual_rows,omitzero"`
+	ActualTime types.Null[float64] `json:"actual_time,omitzero"`
+	Children   []*PlanNode         `json:"children,omitempty"`
+}
+
+// PlanResult is the result of an explained query, rows have the plan output of the database.
+type PlanResult interface {
+	Result
+	Plan() *PlanNode
+}
+
+// MultiResult is the result of a script, Results are the result sets of all statements in order.
+type MultiResult interface {
+	Result
+	Results() []Result
+}
+
+// RowStream is a query result read row by row, Rows must be iterated to release the query.
+type RowStream struct {
+	Columns []string
+	// ColumnTypes of typed results, nil otherwise.
+	ColumnTypes []ColumnInfo
+	Rows        iter.Seq2[[]any, error]
+}
+
+// PoolStats are the connection pool statistics of a database.
+type PoolStats struct {
+	MaxOpenConnections int     `json:"max_open_connections"`
+	OpenConnections    int     `json:"open_connections"`
+	InUse              int     `json:"in_use"`
+	Idle               int     `json:"idle"`
+	WaitCount          int64   `json:"wait_count"`
+	WaitDurationMS     float64 `json:"wait_duration_ms"`
+	MaxIdleClosed      int64   `json:"max_idle_closed"`
+	MaxIdleTimeClosed  int64   `json:"max_idle_time_closed"`
+	MaxLifetimeClosed  int64   `json:"max_lifetime_closed"`
+}
+
+const (
+	DatabaseUp   = "up"
+	DatabaseDown = "down"
+)
+
+// DatabaseStatus is the availability of a database.
+type DatabaseStatus struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	ReadOnly bool   `json:"read_only,omitempty"`
+	// Required databases must be up for readiness.
+	Required bool `json:"required,omitempty"`
+	// Status is up or down, Error is the last connection or ping error.
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	LastCheck time.Time `json:"last_check,omitzero"`
+}
+
 type Database interface {
-	DatabaseList() []string
+	// DatabaseList returns the databases with their availability sorted by name.
+	DatabaseList() []DatabaseStatus
+	// PoolStats returns the connection pool statistics of the databases by name.
+	PoolStats() map[string]PoolStats
+	// QueryTimeout returns the default timeout of cells on the database, 0 is no timeout.
+	QueryTimeout(name string) time.Duration
+	// ReadOnly reports whether the database only allows reads.
+	ReadOnly(name string) bool
+	// Ping checks the connection of the database.
+	Ping(ctx context.Context, name string) error
+
+	Query(ctx context.Context, name, query string, params map[string]any, limit int64, typed bool, snapshot Snapshot) (Result, error)
+	QueryStream(ctx context.Context, name, query string, params map[string]any, limit int64, typed bool, snapshot Snapshot) (*RowStream, error)
+	Exec(ctx context.Context, name, query string, params map[string]any) (Result, error)
+	Script(ctx context.Context, name, script string, params map[string]any, limit int64, typed bool) (Result, error)
+	Explain(ctx context.Context, name, query string, params map[string]any) (Result, error)
+
+	IterGet(ctx context.Context, name, query string, params map[string]any, mapType MapType, lob LOB, snapshot Snapshot) ([]string, iter.Seq2[[]any, error], error)
+	IterSet(ctx context.Context, name, table string, wipe bool, skipError SkipError, mapType MapType, lob LOB, batch int, columns []string, rows iter.Seq2[[]any, error]) (Result, error)
+	// IterMap maps rows read from the database name to the destination mapping.
+	IterMap(name string, columns []string, mapType MapType, rows iter.Seq2[[]any, error]) iter.Seq2[[]any, error]
 
-	Query(ctx context.Context, name, query string, limit int64) (Result, error)
-	Exec(ctx context.Context, name, query string) (Result, error)
+	// WithSnapshot returns a context sharing snapshot transactions between reads, release ends them.
+	WithSnapshot(ctx context.Context) (context.Context, func())
+	// WithExportedSnapshot returns a context where snapshot reads of PostgreSQL import one exported snapshot of the database,
+	// reads keep their own transactions after release ends the exporting ones.
+	WithExportedSnapshot(ctx context.Context) (context.Context, func())
 
-	IterGet(ctx context.Context, name, query string, mapType MapType) ([]string, iter.Seq2[[]any, error], error)
-	IterSet(ctx context.Context, name, table string, wipe bool, skipError SkipError, mapType MapType, batch int, columns []string, rows iter.Seq2[[]any, error]) (Result, error)
+	// SetDatabase adds the database or replaces the one with the same name, the old pool is closed.
+	// A database which can't be connected is added as unavailable.
+	SetDatabase(ctx context.Context, conn *Connection) error
+	// RemoveDatabase removes the database and closes its pool.
+	RemoveDatabase(name string) error
+	// TestDatabase connects to the database and closes the connection.
+	TestDatabase(ctx context.Context, conn *Connection) error
 }
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cast"
+)
+
+// params resolves the parameters of the cell from values.
+func params(cell *Cell, values map[string]any) (map[string]any, error) {
+	if len(cell.Params) == 0 {
+		return nil, nil
+	}
+
+	result := make(map[string]any, len(cell.Params))
+	for _, p := range cell.Params {
+		if p.Name == "" {
+			return nil, fmt.Errorf("parameter name is empty; %w", ErrBadRequest)
+		}
+
+		v, ok := lookupPath(values, p.Path)
+		if !ok {
+			if p.Required {
+				return nil, fmt.Errorf("parameter %s: value of %s not found; %w", p.Name, p.Path, ErrBadRequest)
+			}
+
+			v = p.Default
+		}
+
+		v, err := convertParam(v, p.Type)
+		if err != nil {
+			return nil, fmt.Errorf("parameter %s: %w", p.Name, err)
+		}
+
+		result[p.Name] = v
+	}
+
+	return result, nil
+}
+
+// lookupPath returns the value in dot separated path of maps and slices.
+func lookupPath(values map[string]any, path string) (any, bool) {
+	if path == "" {
+		return nil, false
+	}
+
+	var current any = values
+	for key := range strings.SplitSeq(path, ".") {
+		switch v := current.(type) {
+		case map[string]any:
+			next, ok := v[key]
+			if !ok {
+				return nil, false
+			}
+
+			current = next
+		case []map[string]any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+
+			current = v[i]
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+
+			current = v[i]
+		default:
+			return nil, false
+		}
+	}
+
+	return current, true
+}
+
+func convertParam(v any, typ string) (any, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	var (
+		result any
+		err    error
+	)
+
+	switch typ {
+	case "":
+		return v, nil
+	case "string":
+		result, err = cast.ToStringE(v)
+	case "integer":
+		result, err = cast.ToInt64E(v)
+	case "number":
+		result, err = cast.ToFloat64E(v)
+	case "boolean":
+		result, err = cast.ToBoolE(v)
+	default:
+		return nil, fmt.Errorf("unknown type %s; %w", typ, ErrBadRequest)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("convert %v to %s: %v; %w", v, typ, err, ErrBadRequest)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParams(t *testing.T) {
+	values := map[string]any{
+		"data": map[string]any{"status": "active", "limit": "10", "count": float64(3)},
+		"cells": map[string]any{
+			"users": []map[string]any{{"id": int64(42)}},
+		},
+	}
+
+	got, err := params(&Cell{Params: []Param{
+		{Name: "status", Path: "data.status"},
+		{Name: "limit", Path: "data.limit", Type: "integer"},
+		{Name: "count", Path: "data.count", Type: "integer"},
+		{Name: "user", Path: "cells.users.0.id"},
+		{Name: "missing", Path: "data.missing", Default: "none"},
+		{Name: "null", Path: "cells.users.1.id"},
+	}}, values)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{
+		"status":  "active",
+		"limit":   int64(10),
+		"count":   int64(3),
+		"user":    int64(42),
+		"missing": "none",
+		"null":    nil,
+	}, got)
+
+	_, err = params(&Cell{Params: []Param{{Name: "status", Path: "data.missing", Required: true}}}, values)
+	require.ErrorIs(t, err, ErrBadRequest)
+
+	_, err = params(&Cell{Params: []Param{{Name: "status", Path: "data.status", Type: "integer"}}}, values)
+	require.ErrorIs(t, err, ErrBadRequest)
+
+	got, err = params(&Cell{}, values)
+	require.NoError(t, err)
+	require.Nil(t, got)
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log/slog"
 	"strconv"
+	"time"
 
 	"github.com/rakunlabs/logi"
 	"github.com/worldline-go/saz/internal/render"
@@ -13,12 +14,31 @@ import (
 type Service struct {
 	db    Database
 	store Storer
+
+	// Cursors keeps open queries of paged cells.
+	Cursors *Cursors
+	// Cache keeps results of cells with cache.
+	Cache *ResultCache
+	// MaxTimeout caps timeouts of cells, 0 is no limit.
+	MaxTimeout time.Duration
+	// HistoryDisabled stops recording cell runs.
+	HistoryDisabled bool
+	// HistoryRetention is the age of deleted history records, 0 keeps them.
+	HistoryRetention time.Duration
+	// HealthTimeout is the timeout of pings of health checks, default 5s.
+	HealthTimeout time.Duration
+	// Secret encrypts datasources of saved connections, connections can't be managed without it.
+	Secret *Secret
+
+	connLocks connectionLocks
 }
 
 func New(db Database, store Storer) *Service {
 	return &Service{
-		db:    db,
-		store: store,
+		db:      db,
+		store:   store,
+		Cursors: &Cursors{},
+		Cache:   &ResultCache{},
 	}
 }
 
@@ -46,47 +66,69 @@ func (s *Service) Run(ctx context.Context, cell *Cell, values map[string]any, de
 		}
 	}()
 
-	content := cell.Content
-	if cell.Template.Enabled {
-		contentRendered, err := render.ExecuteWithData(content, values)
-		if err != nil {
-			return nil, fmt.Errorf("render content: %w", err)
+	start := time.Now()
+	history := &History{Kind: historyKind(cell)}
+
+	defer func() {
+		if result != nil {
+			history.Rows = int64(len(result.Rows()))
+			history.RowsAffected = result.RowsAffected()
 		}
 
-		content = string(contentRendered)
+		s.record(ctx, cell, history, start, err)
+	}()
+
+	ctx, cancel, timeout, err := s.withTimeout(ctx, cell)
+	if err != nil {
+		return nil, err
 	}
+	defer cancel()
 
-	if cell.Mode.V.Enabled {
-		switch cell.Mode.V.Name {
-		case "transfer":
-			if cell.Mode.V.Table == "" {
-				return nil, fmt.Errorf("transfer mode requires a table name; %w", ErrBadRequest)
-			}
-			columns, iterGet, err := s.db.IterGet(ctx, cell.DBType, content, cell.Mode.V.MapType)
-			if err != nil {
-				return nil, fmt.Errorf("get iterator: %w", err)
-			}
+	defer func() {
+		err = timeoutError(ctx, timeout, err)
+	}()
 
-			// TODO: make better handling of iterators
-			defer func() {
-				for range iterGet {
-					return
-				}
-			}()
+	content, cellParams, err := prepare(cell, values)
+	if err != nil {
+		return nil, err
+	}
 
-			result, err := s.db.IterSet(ctx, cell.Mode.V.DBType, cell.Mode.V.Table, cell.Mode.V.Wipe, cell.Mode.V.SkipError, cell.Mode.V.MapType, cell.Mode.V.Batch, columns, iterGet)
-			if err != nil {
-				return nil, fmt.Errorf("set iterator: %w", err)
-			}
+	history.Query, history.Params = content, cellParams
+
+	// dependent cells need the rows of the query, not the plan
+	if _, needed := dependency[cell.Path.V]; cell.Explain.V && !needed {
+		if cell.Mode.V.Enabled && cell.Mode.V.Name == "script" {
+			return nil, fmt.Errorf("explain is not supported for scripts; %w", ErrBadRequest)
+		}
+
+		// transfer cells explain their source query
+		return s.db.Explain(ctx, cell.DBType, content, cellParams)
+	}
 
-			return result, nil
+	if cell.Mode.V.Enabled {
+		switch cell.Mode.V.Name {
+		case "transfer":
+			return s.transfer(ctx, &cell.Mode.V, cell.DBType, content, cellParams, cell.Snapshot)
+		case "script":
+			return s.db.Script(ctx, cell.DBType, content, cellParams, cell.Limit, cell.Typed.V)
 		default:
 			return nil, fmt.Errorf("unsupported mode %s; %w", cell.Mode.V.Name, ErrBadRequest)
 		}
 	}
 
+	// dependencies need all rows, note runs pass a dependency map
+	if cell.Result.V && cell.PageSize > 0 && dependency == nil {
+		return s.openPage(ctx, cell, content, cellParams)
+	}
+
 	if cell.Result.V {
-		result, err := s.db.Query(ctx, cell.DBType, content, cell.Limit)
+		var result Result
+		if cell.Cache.Enabled && !cell.Snapshot.Enabled {
+			result, err = s.cachedQuery(ctx, cell, content, cellParams)
+		} else {
+			result, err = s.db.Query(ctx, cell.DBType, content, cellParams, cell.Limit, cell.Typed.V, cell.Snapshot)
+		}
+
 		if err != nil {
 			return nil, err
 		}
@@ -111,7 +153,27 @@ func (s *Service) Run(ctx context.Context, cell *Cell, values map[string]any, de
 		return result, nil
 	}
 
-	return s.db.Exec(ctx, cell.DBType, content)
+	return s.db.Exec(ctx, cell.DBType, content, cellParams)
+}
+
+// prepare renders the content of the cell and reads its parameters from values.
+func prepare(cell *Cell, values map[string]any) (string, map[string]any, error) {
+	content := cell.Content
+	if cell.Template.Enabled {
+		contentRendered, err := render.ExecuteWithData(content, values)
+		if err != nil {
+			return "", nil, fmt.Errorf("render content: %w", err)
+		}
+
+		content = string(contentRendered)
+	}
+
+	cellParams, err := params(cell, values)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return content, cellParams, nil
 }
 
 func (s *Service) RunNote(ctx context.Context, notePath string, values map[string]any) (err error) {
@@ -135,6 +197,7 @@ func (s *Service) RunNote(ctx context.Context, notePath string, values map[strin
 	}
 
 	logNote := slog.Group("note", slog.String("name", note.Name), slog.String("path", note.Path))
+	ctx = ContextWithNote(ctx, note.Path)
 
 	defer func() {
 		if err != nil {
@@ -146,6 +209,10 @@ func (s *Service) RunNote(ctx context.Context, notePath string, values map[strin
 
 	logi.Ctx(ctx).Info("starting note execution", logNote)
 
+	// snapshot reads of cells see the same database state
+	ctx, release := s.db.WithSnapshot(ctx)
+	defer release()
+
 	for i := range note.Content.Cells {
 		logCell := slog.Group("cell", slog.String("description", note.Content.Cells[i].Description.V), slog.Int("number", i+1))
 		ctxCell := logi.WithContext(ctx, logi.Ctx(ctx).With(logNote, logCell))
@@ -180,30 +247,14 @@ func (s *Service) RunNoteCell(ctx context.Context, notePath string, cellPath str
 		return nil, fmt.Errorf("get note by path %s: %w", notePath, err)
 	}
 
-	var cellNode *Cell
-	for i := range note.Content.Cells {
-		if note.Content.Cells[i].Path.V == cellPath {
-			cellNode = &note.Content.Cells[i]
-			break
-		}
-	}
-
-	if cellNode == nil {
-		cellNumber, err := strconv.Atoi(cellPath)
-		if err != nil || cellNumber < 1 {
-			return nil, fmt.Errorf("invalid cell number; %w", ErrBadRequest)
-		}
-
-		cellNode = &note.Content.Cells[cellNumber-1]
-	}
-
-	if cellNode == nil {
-		return nil, fmt.Errorf("cell %s not found in note %s; %w", cellPath, notePath, ErrNotExists)
+	cellNode, err := findCell(note, cellPath)
+	if err != nil {
+		return nil, err
 	}
 
 	logNote := slog.Group("note", slog.String("name", note.Name), slog.String("path", note.Path))
 	logCell := slog.Group("cell", slog.String("description", cellNode.Description.V), slog.String("path", cellPath))
-	ctxCell := logi.WithContext(ctx, logi.Ctx(ctx).With(logNote, logCell))
+	ctxCell := logi.WithContext(ContextWithNote(ctx, note.Path), logi.Ctx(ctx).With(logNote, logCell))
 
 	defer func() {
 		if err != nil {
@@ -215,9 +266,56 @@ func (s *Service) RunNoteCell(ctx context.Context, notePath string, cellPath str
 
 	logi.Ctx(ctxCell).Info("starting cell execution", logNote, logCell)
 
+	// snapshot reads of the dependencies and the cell see the same database state
+	ctxCell, release := s.db.WithExportedSnapshot(ctxCell)
+	defer release()
+
+	if err := s.runDependencies(ctxCell, note, cellNode, values); err != nil {
+		return nil, err
+	}
+
+	result, err = s.Run(ctxCell, cellNode, values, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if cellNode.Result.V {
+		logi.Ctx(ctxCell).Info("cell result",
+			slog.Int64("rows_affected", result.RowsAffected()),
+			slog.Int("columns", len(result.Columns())),
+			slog.Int("rows", len(result.Rows())),
+			slog.String("duration", result.Duration().String()),
+		)
+	}
+
+	return result, nil
+}
+
+// findCell returns the cell of the note with the path or 1 based number.
+func findCell(note *Note, cellPath string) (*Cell, error) {
+	for i := range note.Content.Cells {
+		if note.Content.Cells[i].Path.V == cellPath {
+			return &note.Content.Cells[i], nil
+		}
+	}
+
+	cellNumber, err := strconv.Atoi(cellPath)
+	if err != nil || cellNumber < 1 {
+		return nil, fmt.Errorf("invalid cell number; %w", ErrBadRequest)
+	}
+
+	if cellNumber > len(note.Content.Cells) {
+		return nil, fmt.Errorf("cell %s not found in note %s; %w", cellPath, note.Path, ErrNotExists)
+	}
+
+	return &note.Content.Cells[cellNumber-1], nil
+}
+
+// runDependencies runs the dependency cells of the cell, their results are added to cells of values.
+func (s *Service) runDependencies(ctx context.Context, note *Note, cell *Cell, values map[string]any) error {
 	dependency := make(map[string]struct{})
-	if cellNode.Dependency.V.Enabled {
-		for _, name := range cellNode.Dependency.V.Names {
+	if cell.Dependency.V.Enabled {
+		for _, name := range cell.Dependency.V.Names {
 			dependency[name] = struct{}{}
 		}
 	}
@@ -232,33 +330,27 @@ func (s *Service) RunNoteCell(ctx context.Context, notePath string, cellPath str
 		}
 
 		if depCell == nil {
-			return nil, fmt.Errorf("dependency cell %s not found in note %s; %w", name, notePath, ErrNotExists)
+			return fmt.Errorf("dependency cell %s not found in note %s; %w", name, note.Path, ErrNotExists)
 		}
 
-		if _, err := s.Run(ctxCell, depCell, values, dependency); err != nil {
-			return nil, fmt.Errorf("execute dependency cell %s: %w", name, err)
+		if _, err := s.Run(ctx, depCell, values, dependency); err != nil {
+			return fmt.Errorf("execute dependency cell %s: %w", name, err)
 		}
 	}
 
-	result, err = s.Run(ctxCell, cellNode, values, nil)
-	if err != nil {
-		return nil, err
-	}
+	return nil
+}
 
-	if cellNode.Result.V {
-		logi.Ctx(ctxCell).Info("cell result",
-			slog.Int64("rows_affected", result.RowsAffected()),
-			slog.Int("columns", len(result.Columns())),
-			slog.Int("rows", len(result.Rows())),
-			slog.String("duration", result.Duration().String()),
-		)
-	}
+func (s *Service) DatabaseList() []DatabaseStatus {
+	return s.db.DatabaseList()
+}
 
-	return result, nil
+func (s *Service) PoolStats() map[string]PoolStats {
+	return s.db.PoolStats()
 }
 
-func (s *Service) DatabaseList() []string {
-	return s.db.DatabaseList()
+func (s *Service) ReadOnly(name string) bool {
+	return s.db.ReadOnly(name)
 }
 
 func (s *Service) GetNote(ctx context.Context, id string) (*Note, error) {
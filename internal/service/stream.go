@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/rakunlabs/logi"
+)
+
+// RunStream runs a query cell and returns its rows while they are read from the database.
+// Only query cells with result can be streamed, rows are not kept for dependent cells.
+func (s *Service) RunStream(ctx context.Context, cell *Cell, values map[string]any) (*RowStream, error) {
+	if cell == nil || cell.DBType == "" || cell.Content == "" {
+		return nil, fmt.Errorf("invalid cell; %w", ErrBadRequest)
+	}
+
+	if cell.Mode.V.Enabled || !cell.Result.V {
+		return nil, fmt.Errorf("only query cells with result can be streamed; %w", ErrBadRequest)
+	}
+
+	logCell := slog.Group("cell",
+		slog.String("description", cell.Description.V),
+		slog.String("db_type", cell.DBType),
+	)
+	logi.Ctx(ctx).Info("streaming cell", logCell)
+
+	start := time.Now()
+	history := &History{Kind: "stream"}
+
+	content, cellParams, err := prepare(cell, values)
+	if err != nil {
+		logi.Ctx(ctx).Error("failed to run cell", logCell, slog.String("error", err.Error()))
+		s.record(ctx, cell, history, start, err)
+
+		return nil, err
+	}
+
+	history.Query, history.Params = content, cellParams
+
+	// deadline covers the whole stream, canceled after the rows are read
+	ctx, cancel, timeout, err := s.withTimeout(ctx, cell)
+	if err != nil {
+		s.record(ctx, cell, history, start, err)
+
+		return nil, err
+	}
+
+	stream, err := s.db.QueryStream(ctx, cell.DBType, content, cellParams, cell.Limit, cell.Typed.V, cell.Snapshot)
+	if err != nil {
+		err = timeoutError(ctx, timeout, err)
+		cancel()
+
+		logi.Ctx(ctx).Error("failed to run cell", logCell, slog.String("error", err.Error()))
+		s.record(ctx, cell, history, start, err)
+
+		return nil, err
+	}
+
+	rows := stream.Rows
+	stream.Rows = func(yield func([]any, error) bool) {
+		defer cancel()
+
+		var (
+			count     int64
+			streamErr error
+		)
+
+		// stopped streams are recorded with the rows sent
+		defer func() {
+			history.Rows = count
+			s.record(ctx, cell, history, start, streamErr)
+		}()
+
+		for row, err := range rows {
+			if err != nil {
+				streamErr = timeoutError(ctx, timeout, err)
+				logi.Ctx(ctx).Error("failed to stream cell", logCell, slog.Int64("rows", count), slog.String("error", streamErr.Error()))
+				yield(nil, streamErr)
+
+				return
+			}
+
+			count++
+			if !yield(row, nil) {
+				logi.Ctx(ctx).Warn("cell stream stopped", logCell, slog.Int64("rows", count))
+
+				return
+			}
+		}
+
+		logi.Ctx(ctx).Info("cell streamed successfully",
+			logCell,
+			slog.Int64("rows", count),
+			slog.String("duration", time.Since(start).String()),
+		)
+	}
+
+	return stream, nil
+}
+
+// RunNoteCellStream runs the dependencies of the note cell and streams the rows of the cell.
+func (s *Service) RunNoteCellStream(ctx context.Context, notePath string, cellPath string, values map[string]any) (*RowStream, error) {
+	if notePath == "" {
+		return nil, fmt.Errorf("note path is empty; %w", ErrBadRequest)
+	}
+	if cellPath == "" {
+		return nil, fmt.Errorf("cell is invalid; %w", ErrBadRequest)
+	}
+
+	note, err := s.store.GetWithPath(ctx, notePath)
+	if err != nil {
+		return nil, fmt.Errorf("get note by path %s: %w", notePath, err)
+	}
+
+	cellNode, err := findCell(note, cellPath)
+	if err != nil {
+		return nil, err
+	}
+
+	logNote := slog.Group("note", slog.String("name", note.Name), slog.String("path", note.Path))
+	ctxCell := logi.WithContext(ContextWithNote(ctx, note.Path), logi.Ctx(ctx).With(logNote, slog.String("cell_path", cellPath)))
+
+	// snapshot reads of the dependencies and the cell see the same database state, the stream has its own transaction
+	ctxCell, release := s.db.WithExportedSnapshot(ctxCell)
+	defer release()
+
+	if err := s.runDependencies(ctxCell, note, cellNode, values); err != nil {
+		return nil, err
+	}
+
+	return s.RunStream(ctxCell, cellNode, values)
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// timeout returns the timeout of the cell, the query timeout of the database when the cell has none.
+// It is capped by MaxTimeout, 0 is no timeout.
+func (s *Service) timeout(cell *Cell) (time.Duration, error) {
+	timeout := s.db.QueryTimeout(cell.DBType)
+	if cell.Timeout != "" {
+		cellTimeout, err := time.ParseDuration(cell.Timeout)
+		if err != nil || cellTimeout < 0 {
+			return 0, fmt.Errorf("invalid timeout %q; %w", cell.Timeout, ErrBadRequest)
+		}
+
+		timeout = cellTimeout
+	}
+
+	if s.MaxTimeout > 0 && (timeout == 0 || timeout > s.MaxTimeout) {
+		timeout = s.MaxTimeout
+	}
+
+	return timeout, nil
+}
+
+// withTimeout returns a context with the deadline of the cell timeout.
+func (s *Service) withTimeout(ctx context.Context, cell *Cell) (context.Context, context.CancelFunc, time.Duration, error) {
+	timeout, err := s.timeout(cell)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	if timeout == 0 {
+		return ctx, func() {}, 0, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+
+	return ctx, cancel, timeout, nil
+}
+
+// timeoutError marks err as ErrTimeout when the deadline of ctx is exceeded.
+// Drivers return their own cancel errors, so the context is checked instead of err.
+func timeoutError(ctx context.Context, timeout time.Duration, err error) error {
+	if err == nil || errors.Is(err, ErrTimeout) || !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return err
+	}
+
+	return fmt.Errorf("cell exceeded timeout %s: %w; %w", timeout, err, ErrTimeout)
+}
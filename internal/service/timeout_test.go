@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// slowDatabase waits in Query until the context is done like a long running query.
+type slowDatabase struct {
+	Database
+
+	queryTimeout time.Duration
+}
+
+func (d *slowDatabase) QueryTimeout(string) time.Duration {
+	return d.queryTimeout
+}
+
+func (d *slowDatabase) Query(ctx context.Context, _, _ string, _ map[string]any, _ int64, _ bool, _ Snapshot) (Result, error) {
+	<-ctx.Done()
+
+	// drivers return their own errors on cancel
+	return nil, errors.New("driver: query canceled")
+}
+
+func TestTimeout(t *testing.T) {
+	tests := []struct {
+		name         string
+		cellTimeout  string
+		queryTimeout time.Duration
+		maxTimeout   time.Duration
+		want         time.Duration
+		wantErr      bool
+	}{
+		{name: "none"},
+		{name: "database default", queryTimeout: time.Minute, want: time.Minute},
+		{name: "cell over database", cellTimeout: "10s", queryTimeout: time.Minute, want: 10 * time.Second},
+		{name: "capped", cellTimeout: "1h", maxTimeout: time.Minute, want: time.Minute},
+		{name: "max without timeout", maxTimeout: time.Minute, want: time.Minute},
+		{name: "invalid", cellTimeout: "soon", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(&slowDatabase{queryTimeout: tt.queryTimeout}, nil)
+			s.MaxTimeout = tt.maxTimeout
+
+			got, err := s.timeout(&Cell{DBType: "db", Timeout: tt.cellTimeout})
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrBadRequest)
+
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	s := New(&slowDatabase{}, nil)
+
+	cell := &Cell{DBType: "db", Content: "SELECT pg_sleep(60)", Timeout: "20ms"}
+	cell.Result.V = true
+
+	start := time.Now()
+	_, err := s.Run(t.Context(), cell, map[string]any{}, nil)
+	require.ErrorIs(t, err, ErrTimeout)
+	require.ErrorContains(t, err, "driver: query canceled")
+	require.Less(t, time.Since(start), time.Second)
+}
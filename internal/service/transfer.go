@@ -0,0 +1,314 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/rakunlabs/logi"
+)
+
+const (
+	OnErrorAbort    = "abort"
+	OnErrorContinue = "continue"
+)
+
+func (s *Service) transfer(ctx context.Context, mode *Mode, dbType, content string, params map[string]any, snapshot Snapshot) (Result, error) {
+	if len(mode.Destinations) == 0 {
+		if mode.Table == "" {
+			return nil, fmt.Errorf("transfer mode requires a table name; %w", ErrBadRequest)
+		}
+
+		if s.db.ReadOnly(mode.DBType) {
+			return nil, fmt.Errorf("database %s is read only, it can't be a transfer destination; %w", mode.DBType, ErrForbidden)
+		}
+
+		columns, iterGet, err := s.db.IterGet(ctx, dbType, content, params, mode.MapType, mode.LOB, snapshot)
+		if err != nil {
+			return nil, fmt.Errorf("get iterator: %w", err)
+		}
+
+		// TODO: make better handling of iterators
+		defer func() {
+			for range iterGet {
+				return
+			}
+		}()
+
+		result, err := s.db.IterSet(ctx, mode.DBType, mode.Table, mode.Wipe, mode.SkipError, mode.MapType, mode.LOB, mode.Batch, columns, iterGet)
+		if err != nil {
+			return nil, fmt.Errorf("set iterator: %w", err)
+		}
+
+		return result, nil
+	}
+
+	switch mode.OnError {
+	case "", OnErrorAbort, OnErrorContinue:
+	default:
+		return nil, fmt.Errorf("unsupported on_error policy %s; %w", mode.OnError, ErrBadRequest)
+	}
+
+	destinations := make([]Destination, 0, len(mode.Destinations)+1)
+	if mode.Table != "" {
+		destinations = append(destinations, Destination{
+			DBType: mode.DBType,
+			Table:  mode.Table,
+			Wipe:   mode.Wipe,
+		})
+	}
+	destinations = append(destinations, mode.Destinations...)
+
+	for i := range destinations {
+		if destinations[i].Table == "" {
+			return nil, fmt.Errorf("transfer destination %d requires a table name; %w", i+1, ErrBadRequest)
+		}
+
+		if s.db.ReadOnly(destinations[i].DBType) {
+			return nil, fmt.Errorf("database %s of transfer destination %d is read only; %w", destinations[i].DBType, i+1, ErrForbidden)
+		}
+
+		// destinations without own mapping use the mapping of the mode
+		if !destinations[i].MapType.Enabled {
+			destinations[i].MapType = MapType{
+				Enabled:     mode.MapType.Enabled,
+				Destination: mode.MapType.Destination,
+			}
+		}
+	}
+
+	start := time.Now()
+
+	// source side only scans columns, destination mapping is applied per destination
+	columns, iterGet, err := s.db.IterGet(ctx, dbType, content, params, sourceMapType(mode.MapType, destinations), mode.LOB, snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("get iterator: %w", err)
+	}
+
+	defer func() {
+		for range iterGet {
+			return
+		}
+	}()
+
+	writers := make([]func(ctx context.Context, rows iter.Seq2[[]any, error]) (Result, error), 0, len(destinations))
+	for _, dest := range destinations {
+		writers = append(writers, func(ctx context.Context, rows iter.Seq2[[]any, error]) (Result, error) {
+			return s.db.IterSet(ctx, dest.DBType, dest.Table, dest.Wipe, mode.SkipError, dest.MapType, mode.LOB, mode.Batch, columns, s.db.IterMap(dbType, columns, dest.MapType, rows))
+		})
+	}
+
+	results := fanOut(ctx, iterGet, writers, mode.OnError == OnErrorContinue)
+
+	transferResult := &TransferResult{
+		columns: []string{"db_type", "table", "status", "rows_affected", "duration", "error"},
+	}
+
+	var errs []error
+	for i, r := range results {
+		status := "success"
+		var rowsAffected int64
+		var duration, errMsg string
+
+		if r.err != nil {
+			status = "failed"
+			errMsg = r.err.Error()
+			errs = append(errs, fmt.Errorf("destination %s %s: %w", destinations[i].DBType, destinations[i].Table, r.err))
+
+			logi.Ctx(ctx).Error("transfer destination failed",
+				slog.String("db_type", destinations[i].DBType),
+				slog.String("table", destinations[i].Table),
+				slog.String("error", errMsg),
+			)
+		} else if r.result != nil {
+			rowsAffected = r.result.RowsAffected()
+			duration = r.result.Duration().Truncate(time.Microsecond).String()
+			transferResult.rowsAffected += rowsAffected
+		}
+
+		transferResult.rows = append(transferResult.rows, []any{
+			destinations[i].DBType, destinations[i].Table, status, rowsAffected, duration, errMsg,
+		})
+	}
+
+	transferResult.duration = time.Since(start)
+
+	if len(errs) > 0 && (mode.OnError != OnErrorContinue || len(errs) == len(results)) {
+		return nil, fmt.Errorf("transfer: %w", errors.Join(errs...))
+	}
+
+	return transferResult, nil
+}
+
+// sourceMapType is the mapping of the source of a fan-out transfer.
+// Columns decoded by any destination are added without a type, the source keeps them as read instead of sanitizing.
+func sourceMapType(mapType MapType, destinations []Destination) MapType {
+	source := MapType{
+		Enabled: mapType.Enabled,
+		Column:  mapType.Column,
+	}
+
+	for _, dest := range destinations {
+		if !dest.MapType.Enabled {
+			continue
+		}
+
+		for name, colType := range dest.MapType.Destination {
+			if !colType.Encoding.Enabled {
+				continue
+			}
+
+			if source.Destination == nil {
+				source.Destination = make(map[string]ColumnTypeTemplate)
+			}
+
+			source.Destination[name] = ColumnTypeTemplate{Encoding: colType.Encoding}
+		}
+	}
+
+	return source
+}
+
+// /////////////////////////////////////////////
+
+type fanOutResult struct {
+	result Result
+	err    error
+}
+
+type fanOutItem struct {
+	row []any
+	err error
+}
+
+type fanOutBranch struct {
+	rows chan fanOutItem
+	ack  chan struct{}
+	done chan struct{}
+
+	active bool
+}
+
+// fanOut reads source once and writes every row to all writers concurrently.
+//
+// Rows are handed over in lock step, the next row is read from the source only after
+// every active writer is done with the current one because scanned rows may share memory.
+// When continueOnError is false, the first failing writer cancels the others which are still writing,
+// writers which already committed are not rolled back.
+func fanOut(ctx context.Context, source iter.Seq2[[]any, error], writers []func(ctx context.Context, rows iter.Seq2[[]any, error]) (Result, error), continueOnError bool) []fanOutResult {
+	ctxWriter, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]fanOutResult, len(writers))
+	branches := make([]*fanOutBranch, len(writers))
+
+	wg := sync.WaitGroup{}
+	for i, writer := range writers {
+		b := &fanOutBranch{
+			rows:   make(chan fanOutItem),
+			ack:    make(chan struct{}, 1),
+			done:   make(chan struct{}),
+			active: true,
+		}
+		branches[i] = b
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(b.done)
+
+			results[i].result, results[i].err = writer(ctxWriter, func(yield func([]any, error) bool) {
+				for item := range b.rows {
+					next := yield(item.row, item.err)
+					b.ack <- struct{}{}
+
+					if !next {
+						return
+					}
+				}
+			})
+
+			if results[i].err != nil && !continueOnError {
+				cancel()
+			}
+		}()
+	}
+
+	for row, err := range source {
+		if ctxWriter.Err() != nil {
+			break
+		}
+
+		sent := 0
+		for _, b := range branches {
+			if !b.active {
+				continue
+			}
+
+			select {
+			case b.rows <- fanOutItem{row: row, err: err}:
+				sent++
+			case <-b.done:
+				b.active = false
+			}
+		}
+
+		if sent == 0 {
+			break
+		}
+
+		for _, b := range branches {
+			if !b.active {
+				continue
+			}
+
+			select {
+			case <-b.ack:
+			case <-b.done:
+				b.active = false
+			}
+		}
+	}
+
+	for _, b := range branches {
+		close(b.rows)
+	}
+
+	wg.Wait()
+
+	return results
+}
+
+// /////////////////////////////////////////////
+
+// TransferResult holds the status of each destination of a fan-out transfer.
+type TransferResult struct {
+	columns      []string
+	rows         [][]any
+	rowsAffected int64
+	duration     time.Duration
+}
+
+func (r *TransferResult) Columns() []string {
+	return r.columns
+}
+
+func (r *TransferResult) ColumnTypes() []ColumnInfo {
+	return nil
+}
+
+func (r *TransferResult) Rows() [][]any {
+	return r.rows
+}
+
+func (r *TransferResult) RowsAffected() int64 {
+	return r.rowsAffected
+}
+
+func (r *TransferResult) Duration() time.Duration {
+	return r.duration
+}
@@ -0,0 +1,227 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func sourceRows(n int) iter.Seq2[[]any, error] {
+	return func(yield func([]any, error) bool) {
+		// same slice is reused for every row like the scanner does
+		row := make([]any, 1)
+		for i := range n {
+			row[0] = i
+			if !yield(row, nil) {
+				return
+			}
+		}
+
+		yield(nil, nil)
+	}
+}
+
+func collectWriter(got *[]int) func(ctx context.Context, rows iter.Seq2[[]any, error]) (Result, error) {
+	return func(ctx context.Context, rows iter.Seq2[[]any, error]) (Result, error) {
+		for row, err := range rows {
+			if err != nil {
+				return nil, err
+			}
+
+			if len(row) == 0 {
+				continue
+			}
+
+			*got = append(*got, row[0].(int))
+		}
+
+		return &TransferResult{rowsAffected: int64(len(*got))}, nil
+	}
+}
+
+func failWriter(after int) func(ctx context.Context, rows iter.Seq2[[]any, error]) (Result, error) {
+	return func(ctx context.Context, rows iter.Seq2[[]any, error]) (Result, error) {
+		count := 0
+		for range rows {
+			count++
+			if count == after {
+				return nil, errors.New("write failed")
+			}
+		}
+
+		return &TransferResult{}, nil
+	}
+}
+
+func waitWriter(ctx context.Context, rows iter.Seq2[[]any, error]) (Result, error) {
+	for range rows {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(time.Millisecond):
+		}
+	}
+
+	// commit fails when the context is canceled
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return &TransferResult{}, nil
+}
+
+func TestFanOut(t *testing.T) {
+	var first, second []int
+
+	results := fanOut(t.Context(), sourceRows(10), []func(ctx context.Context, rows iter.Seq2[[]any, error]) (Result, error){
+		collectWriter(&first),
+		collectWriter(&second),
+	}, false)
+
+	require.Len(t, results, 2)
+	for _, r := range results {
+		require.NoError(t, r.err)
+		require.Equal(t, int64(10), r.result.RowsAffected())
+	}
+
+	require.Equal(t, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, first)
+	require.Equal(t, first, second)
+}
+
+func TestFanOutAbort(t *testing.T) {
+	results := fanOut(t.Context(), sourceRows(100), []func(ctx context.Context, rows iter.Seq2[[]any, error]) (Result, error){
+		failWriter(3),
+		waitWriter,
+	}, false)
+
+	require.EqualError(t, results[0].err, "write failed")
+	require.ErrorIs(t, results[1].err, context.Canceled)
+}
+
+func TestFanOutContinue(t *testing.T) {
+	var got []int
+
+	results := fanOut(t.Context(), sourceRows(10), []func(ctx context.Context, rows iter.Seq2[[]any, error]) (Result, error){
+		failWriter(3),
+		collectWriter(&got),
+	}, true)
+
+	require.EqualError(t, results[0].err, "write failed")
+	require.NoError(t, results[1].err)
+	require.Len(t, got, 10)
+}
+
+// readOnlyDatabase fails calls other than ReadOnly, transfers must stop before reading the source.
+type readOnlyDatabase struct {
+	Database
+
+	readOnly map[string]bool
+}
+
+func (d *readOnlyDatabase) ReadOnly(name string) bool {
+	return d.readOnly[name]
+}
+
+func TestTransferReadOnly(t *testing.T) {
+	s := New(&readOnlyDatabase{readOnly: map[string]bool{"replica": true}}, nil)
+
+	_, err := s.transfer(t.Context(), &Mode{DBType: "replica", Table: "users"}, "source", "SELECT * FROM users", nil, Snapshot{})
+	require.ErrorIs(t, err, ErrForbidden)
+
+	_, err = s.transfer(t.Context(), &Mode{
+		Destinations: []Destination{
+			{DBType: "primary", Table: "users"},
+			{DBType: "replica", Table: "users"},
+		},
+	}, "source", "SELECT * FROM users", nil, Snapshot{})
+	require.ErrorIs(t, err, ErrForbidden)
+	require.ErrorContains(t, err, "destination 2")
+}
+
+// transferDatabase records the source mapping of a transfer and counts rows written to every destination.
+type transferDatabase struct {
+	Database
+
+	source  MapType
+	mu      sync.Mutex
+	written map[string]int64
+}
+
+func (d *transferDatabase) ReadOnly(string) bool { return false }
+
+func (d *transferDatabase) IterGet(_ context.Context, _, _ string, _ map[string]any, mapType MapType, _ LOB, _ Snapshot) ([]string, iter.Seq2[[]any, error], error) {
+	d.source = mapType
+
+	return []string{"id", "name"}, func(yield func([]any, error) bool) {
+		for i := range 3 {
+			if !yield([]any{i, "caf\xe9"}, nil) {
+				return
+			}
+		}
+
+		yield(nil, nil)
+	}, nil
+}
+
+func (d *transferDatabase) IterMap(_ string, _ []string, _ MapType, rows iter.Seq2[[]any, error]) iter.Seq2[[]any, error] {
+	return rows
+}
+
+func (d *transferDatabase) IterSet(_ context.Context, name, _ string, _ bool, _ SkipError, _ MapType, _ LOB, _ int, _ []string, rows iter.Seq2[[]any, error]) (Result, error) {
+	var count int64
+	for row, err := range rows {
+		if err != nil {
+			return nil, err
+		}
+
+		if len(row) > 0 {
+			count++
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.written[name] = count
+
+	return &TransferResult{rowsAffected: count}, nil
+}
+
+func TestTransferFanOutEncoding(t *testing.T) {
+	db := &transferDatabase{written: make(map[string]int64)}
+	s := New(db, nil)
+
+	latin1 := Encoding{Enabled: true, Coding: "ISO 8859-1"}
+
+	result, err := s.transfer(t.Context(), &Mode{
+		MapType: MapType{
+			Enabled: true,
+			Column:  map[string]ColumnType{"name": {Type: "string"}},
+			Destination: map[string]ColumnTypeTemplate{
+				"name": {Type: "string", Encoding: latin1},
+			},
+		},
+		Destinations: []Destination{
+			{DBType: "first", Table: "users"},
+			{DBType: "second", Table: "users", MapType: MapType{
+				Enabled: true,
+				Destination: map[string]ColumnTypeTemplate{
+					"id": {Type: "integer"},
+				},
+			}},
+		},
+	}, "source", "SELECT id, name FROM users", nil, Snapshot{})
+	require.NoError(t, err)
+	require.Equal(t, int64(6), result.RowsAffected())
+	require.Equal(t, map[string]int64{"first": 3, "second": 3}, db.written)
+
+	// encoded column reaches destinations as read, only the destination decodes it
+	require.True(t, db.source.Enabled)
+	require.Equal(t, map[string]ColumnType{"name": {Type: "string"}}, db.source.Column)
+	require.Equal(t, map[string]ColumnTypeTemplate{"name": {Encoding: latin1}}, db.source.Destination)
+}
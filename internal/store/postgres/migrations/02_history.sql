@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS ${table_prefix}history (
+    id TEXT PRIMARY KEY,
+    user_name TEXT,
+    db_type TEXT NOT NULL,
+    note_path TEXT,
+    cell_path TEXT,
+    kind TEXT NOT NULL,
+    query TEXT NOT NULL,
+    params json,
+    duration_ms DOUBLE PRECISION NOT NULL,
+    rows BIGINT NOT NULL,
+    rows_affected BIGINT NOT NULL,
+    status TEXT NOT NULL,
+    error TEXT,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_${table_prefix}history_created_at ON ${table_prefix}history(created_at);
+CREATE INDEX IF NOT EXISTS idx_${table_prefix}history_user_name ON ${table_prefix}history(user_name, created_at);
+CREATE INDEX IF NOT EXISTS idx_${table_prefix}history_db_type ON ${table_prefix}history(db_type, created_at);
+CREATE INDEX IF NOT EXISTS idx_${table_prefix}history_note_path ON ${table_prefix}history(note_path, created_at);
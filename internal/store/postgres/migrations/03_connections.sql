@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS ${table_prefix}connections (
+    name TEXT PRIMARY KEY,
+    db_type TEXT NOT NULL,
+    datasource TEXT NOT NULL,
+    options json NOT NULL,
+    updated_by TEXT,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
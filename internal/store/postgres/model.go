@@ -19,3 +19,43 @@ type NoteIDName struct {
 	ID   string `db:"id"`
 	Name string `db:"name"`
 }
+
+type History struct {
+	ID           string                     `db:"id"`
+	UserName     types.Null[string]         `db:"user_name"`
+	DBType       string                     `db:"db_type"`
+	NotePath     types.Null[string]         `db:"note_path"`
+	CellPath     types.Null[string]         `db:"cell_path"`
+	Kind         string                     `db:"kind"`
+	Query        string                     `db:"query"`
+	Params       types.JSON[map[string]any] `db:"params"`
+	DurationMS   float64                    `db:"duration_ms"`
+	Rows         int64                      `db:"rows"`
+	RowsAffected int64                      `db:"rows_affected"`
+	Status       string                     `db:"status"`
+	Error        types.Null[string]         `db:"error"`
+	CreatedAt    types.Null[types.Time]     `db:"created_at"`
+}
+
+type Connection struct {
+	Name   string `db:"name"       goqu:"skipupdate"`
+	DBType string `db:"db_type"`
+	// Datasource is encrypted by the service.
+	Datasource string                        `db:"datasource"`
+	Options    types.JSON[ConnectionOptions] `db:"options"`
+	UpdatedBy  types.Null[string]            `db:"updated_by"`
+	CreatedAt  types.Null[types.Time]        `db:"created_at" goqu:"skipupdate"`
+	UpdatedAt  types.Null[types.Time]        `db:"updated_at"`
+}
+
+// ConnectionOptions are the settings of a connection without own column.
+type ConnectionOptions struct {
+	DBSchema        string   `json:"db_schema,omitempty"`
+	InitStatements  []string `json:"init_statements,omitempty"`
+	QueryTimeout    string   `json:"query_timeout,omitempty"`
+	ReadOnly        bool     `json:"read_only,omitempty"`
+	MaxOpenConns    int      `json:"max_open_conns,omitempty"`
+	MaxIdleConns    int      `json:"max_idle_conns,omitempty"`
+	ConnMaxLifetime string   `json:"conn_max_lifetime,omitempty"`
+	ConnMaxIdleTime string   `json:"conn_max_idle_time,omitempty"`
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"time"
 
 	"github.com/rakunlabs/tummy"
 	"github.com/worldline-go/conn/database"
@@ -24,8 +25,11 @@ type Postgres struct {
 	db   *sql.DB
 	goqu *goqu.Database
 
-	tableNotes exp.IdentifierExpression
-	tableCron  exp.IdentifierExpression
+	tableNotes   exp.IdentifierExpression
+	tableCron    exp.IdentifierExpression
+	tableHistory exp.IdentifierExpression
+
+	tableConnections exp.IdentifierExpression
 }
 
 func New(ctx context.Context, cfg *config.StorePostgres) (*Postgres, error) {
@@ -62,10 +66,13 @@ func conn(cfg *config.StorePostgres, dbConn *sql.DB) (*Postgres, error) {
 	dbGoqu := goqu.New("postgres", dbConn)
 
 	return &Postgres{
-		db:         dbConn,
-		goqu:       dbGoqu,
-		tableNotes: goqu.S(cfg.DBSchema).Table(cfg.TablePrefix + "notes"),
-		tableCron:  goqu.S(cfg.DBSchema).Table(cfg.TablePrefix + "cron"),
+		db:           dbConn,
+		goqu:         dbGoqu,
+		tableNotes:   goqu.S(cfg.DBSchema).Table(cfg.TablePrefix + "notes"),
+		tableCron:    goqu.S(cfg.DBSchema).Table(cfg.TablePrefix + "cron"),
+		tableHistory: goqu.S(cfg.DBSchema).Table(cfg.TablePrefix + "history"),
+
+		tableConnections: goqu.S(cfg.DBSchema).Table(cfg.TablePrefix + "connections"),
 	}, nil
 }
 
@@ -77,6 +84,14 @@ func (s *Postgres) Close() {
 	}
 }
 
+func (s *Postgres) Ping(ctx context.Context) error {
+	if err := s.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("ping store postgres: %w", err)
+	}
+
+	return nil
+}
+
 // ////////////////////////////////////////
 
 func (s *Postgres) Get(ctx context.Context, id string) (*service.Note, error) {
@@ -193,3 +208,210 @@ func (s *Postgres) Delete(ctx context.Context, id string) error {
 
 	return nil
 }
+
+// ////////////////////////////////////////
+
+func (s *Postgres) AddHistory(ctx context.Context, history *service.History) error {
+	dbHistory := History{
+		ID:           history.ID,
+		UserName:     types.NewNullWithValid(history.User, history.User != ""),
+		DBType:       history.DBType,
+		NotePath:     types.NewNullWithValid(history.Note, history.Note != ""),
+		CellPath:     types.NewNullWithValid(history.Cell, history.Cell != ""),
+		Kind:         history.Kind,
+		Query:        history.Query,
+		Params:       types.JSON[map[string]any]{V: history.Params, Valid: len(history.Params) > 0},
+		DurationMS:   history.DurationMS,
+		Rows:         history.Rows,
+		RowsAffected: history.RowsAffected,
+		Status:       history.Status,
+		Error:        types.NewNullWithValid(history.Error, history.Error != ""),
+		CreatedAt:    types.NewTimeNull(tummy.Now()),
+	}
+
+	if _, err := s.goqu.Insert(s.tableHistory).Rows(dbHistory).Executor().ExecContext(ctx); err != nil {
+		return fmt.Errorf("exec insert history: %w", err)
+	}
+
+	history.CreatedAt = dbHistory.CreatedAt
+
+	return nil
+}
+
+func (s *Postgres) GetHistory(ctx context.Context, filter service.HistoryFilter) ([]service.History, error) {
+	query := s.goqu.From(s.tableHistory).Order(goqu.C("created_at").Desc(), goqu.C("id").Desc())
+
+	if filter.User != "" {
+		query = query.Where(goqu.Ex{"user_name": filter.User})
+	}
+
+	if filter.DBType != "" {
+		query = query.Where(goqu.Ex{"db_type": filter.DBType})
+	}
+
+	if filter.Note != "" {
+		query = query.Where(goqu.Ex{"note_path": filter.Note})
+	}
+
+	if !filter.From.IsZero() {
+		query = query.Where(goqu.C("created_at").Gte(filter.From))
+	}
+
+	if !filter.To.IsZero() {
+		query = query.Where(goqu.C("created_at").Lt(filter.To))
+	}
+
+	if filter.Limit > 0 {
+		query = query.Limit(uint(filter.Limit))
+	}
+
+	if filter.Offset > 0 {
+		query = query.Offset(uint(filter.Offset))
+	}
+
+	var rows []History
+	if err := query.ScanStructsContext(ctx, &rows); err != nil {
+		return nil, fmt.Errorf("get history: %w", err)
+	}
+
+	histories := make([]service.History, len(rows))
+	for i, row := range rows {
+		histories[i] = service.History{
+			ID:           row.ID,
+			User:         row.UserName.V,
+			DBType:       row.DBType,
+			Note:         row.NotePath.V,
+			Cell:         row.CellPath.V,
+			Kind:         row.Kind,
+			Query:        row.Query,
+			Params:       row.Params.V,
+			DurationMS:   row.DurationMS,
+			Rows:         row.Rows,
+			RowsAffected: row.RowsAffected,
+			Status:       row.Status,
+			Error:        row.Error.V,
+			CreatedAt:    row.CreatedAt,
+		}
+	}
+
+	return histories, nil
+}
+
+func (s *Postgres) DeleteHistory(ctx context.Context, before time.Time) (int64, error) {
+	result, err := s.goqu.Delete(s.tableHistory).Where(goqu.C("created_at").Lt(before)).Executor().ExecContext(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("delete history before %s: %w", before.Format(time.RFC3339), err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("get rows affected after delete history: %w", err)
+	}
+
+	return rowsAffected, nil
+}
+
+// ////////////////////////////////////////
+
+func (s *Postgres) GetConnections(ctx context.Context) ([]service.Connection, error) {
+	var rows []Connection
+	if err := s.goqu.From(s.tableConnections).Order(goqu.C("name").Asc()).ScanStructsContext(ctx, &rows); err != nil {
+		return nil, fmt.Errorf("get connections: %w", err)
+	}
+
+	conns := make([]service.Connection, len(rows))
+	for i, row := range rows {
+		conns[i] = serviceConnection(row)
+	}
+
+	return conns, nil
+}
+
+func (s *Postgres) GetConnection(ctx context.Context, name string) (*service.Connection, error) {
+	if name == "" {
+		return nil, fmt.Errorf("connection name is empty; %w", service.ErrBadRequest)
+	}
+
+	var row Connection
+	isFound, err := s.goqu.From(s.tableConnections).Where(goqu.Ex{"name": name}).ScanStructContext(ctx, &row)
+	if err != nil {
+		return nil, fmt.Errorf("get connection %s: %w", name, err)
+	}
+
+	if !isFound {
+		return nil, fmt.Errorf("connection %s not found; %w", name, service.ErrNotExists)
+	}
+
+	conn := serviceConnection(row)
+
+	return &conn, nil
+}
+
+func (s *Postgres) SaveConnection(ctx context.Context, conn *service.Connection) error {
+	dbConn := Connection{
+		Name:       conn.Name,
+		DBType:     conn.DBType,
+		Datasource: conn.Datasource,
+		Options: types.NewJSON(ConnectionOptions{
+			DBSchema:        conn.DBSchema,
+			InitStatements:  conn.InitStatements,
+			QueryTimeout:    conn.QueryTimeout,
+			ReadOnly:        conn.ReadOnly,
+			MaxOpenConns:    conn.MaxOpenConns,
+			MaxIdleConns:    conn.MaxIdleConns,
+			ConnMaxLifetime: conn.ConnMaxLifetime,
+			ConnMaxIdleTime: conn.ConnMaxIdleTime,
+		}),
+		UpdatedBy: types.NewNull(service.UserContext(ctx)),
+		UpdatedAt: types.NewTimeNull(tummy.Now()),
+		CreatedAt: types.NewTimeNull(tummy.Now()),
+	}
+
+	_, err := s.goqu.Insert(s.tableConnections).Rows(dbConn).OnConflict(goqu.DoUpdate("name", dbConn)).Executor().ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("exec upsert connection: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Postgres) DeleteConnection(ctx context.Context, name string) error {
+	if name == "" {
+		return fmt.Errorf("connection name is empty; %w", service.ErrBadRequest)
+	}
+
+	result, err := s.goqu.Delete(s.tableConnections).Where(goqu.Ex{"name": name}).Executor().ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("delete connection %s: %w", name, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("get rows affected after delete connection %s: %w", name, err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("connection %s not found; %w", name, service.ErrNotExists)
+	}
+
+	return nil
+}
+
+func serviceConnection(row Connection) service.Connection {
+	return service.Connection{
+		Name:            row.Name,
+		DBType:          row.DBType,
+		Datasource:      row.Datasource,
+		DBSchema:        row.Options.V.DBSchema,
+		InitStatements:  row.Options.V.InitStatements,
+		QueryTimeout:    row.Options.V.QueryTimeout,
+		ReadOnly:        row.Options.V.ReadOnly,
+		MaxOpenConns:    row.Options.V.MaxOpenConns,
+		MaxIdleConns:    row.Options.V.MaxIdleConns,
+		ConnMaxLifetime: row.Options.V.ConnMaxLifetime,
+		ConnMaxIdleTime: row.Options.V.ConnMaxIdleTime,
+		UpdatedBy:       row.UpdatedBy,
+		CreatedAt:       row.CreatedAt,
+		UpdatedAt:       row.UpdatedAt,
+	}
+}
@@ -1,6 +1,7 @@
 package postgres
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
@@ -95,3 +96,85 @@ func (s *PostgresSuite) Test_Save() {
 	// UpdatedAt should be 10 seconds different
 	require.Equal(s.T(), noteByPath.UpdatedAt.V.Sub(getUpdatedAt.Time), 10*time.Second, "UpdatedAt should be different")
 }
+
+func (s *PostgresSuite) Test_History() {
+	postgres, err := conn(&config.StorePostgres{}, s.container.Sql())
+	require.NoError(s.T(), err)
+
+	tummy.Pause()
+	tummy.SetTime(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	for i, user := range []string{"alice", "bob", "alice"} {
+		err := postgres.AddHistory(s.T().Context(), &service.History{
+			ID:     fmt.Sprintf("history-%d", i),
+			User:   user,
+			DBType: "replica",
+			Kind:   "query",
+			Query:  "SELECT * FROM users WHERE id = :id",
+			Params: map[string]any{"id": i},
+			Rows:   1,
+			Status: service.HistorySuccess,
+		})
+		require.NoError(s.T(), err)
+
+		tummy.AddDuration(time.Hour)
+	}
+
+	histories, err := postgres.GetHistory(s.T().Context(), service.HistoryFilter{User: "alice", Limit: 10})
+	require.NoError(s.T(), err)
+	require.Len(s.T(), histories, 2)
+	require.Equal(s.T(), "history-2", histories[0].ID)
+	require.Equal(s.T(), "SELECT * FROM users WHERE id = :id", histories[0].Query)
+
+	histories, err = postgres.GetHistory(s.T().Context(), service.HistoryFilter{
+		From: time.Date(2025, 1, 1, 1, 0, 0, 0, time.UTC),
+		To:   time.Date(2025, 1, 1, 2, 0, 0, 0, time.UTC),
+	})
+	require.NoError(s.T(), err)
+	require.Len(s.T(), histories, 1)
+	require.Equal(s.T(), "bob", histories[0].User)
+
+	deleted, err := postgres.DeleteHistory(s.T().Context(), time.Date(2025, 1, 1, 2, 0, 0, 0, time.UTC))
+	require.NoError(s.T(), err)
+	require.EqualValues(s.T(), 2, deleted)
+}
+
+func (s *PostgresSuite) Test_Connections() {
+	postgres, err := conn(&config.StorePostgres{}, s.container.Sql())
+	require.NoError(s.T(), err)
+
+	ctx := service.ContextWithUser(s.T().Context(), "alice")
+
+	connection := &service.Connection{
+		Name:         "replica",
+		DBType:       "pgx",
+		Datasource:   "encrypted",
+		QueryTimeout: "30s",
+		ReadOnly:     true,
+
+		InitStatements: []string{"SET TIME ZONE 'UTC'"},
+	}
+	require.NoError(s.T(), postgres.SaveConnection(ctx, connection))
+
+	connection.MaxOpenConns = 10
+	require.NoError(s.T(), postgres.SaveConnection(ctx, connection))
+
+	saved, err := postgres.GetConnection(ctx, "replica")
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "encrypted", saved.Datasource)
+	require.Equal(s.T(), "30s", saved.QueryTimeout)
+	require.True(s.T(), saved.ReadOnly)
+	require.Equal(s.T(), 10, saved.MaxOpenConns)
+	require.Equal(s.T(), []string{"SET TIME ZONE 'UTC'"}, saved.InitStatements)
+	require.Equal(s.T(), "alice", saved.UpdatedBy.V)
+
+	conns, err := postgres.GetConnections(ctx)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), conns, 1)
+
+	require.NoError(s.T(), postgres.DeleteConnection(ctx, "replica"))
+	require.ErrorIs(s.T(), postgres.DeleteConnection(ctx, "replica"), service.ErrNotExists)
+
+	_, err = postgres.GetConnection(ctx, "replica")
+	require.ErrorIs(s.T(), err, service.ErrNotExists)
+}